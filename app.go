@@ -0,0 +1,182 @@
+package faas
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// App 持有一个faas服务的全部注册状态:入口、gatt函数、定时函数以及配置
+type App struct {
+	//监听地址,默认取环境变量SU_SERVER_ADDR,为空时为:8080
+	Addr string
+	//优雅退出时等待请求和定时函数完成的时长,默认取环境变量SU_SHUTDOWN_TIMEOUT,为空时为10s
+	ShutdownTimeout time.Duration
+	//路由未通过Timeout指定时的处理时长限制,默认取环境变量SU_REQUEST_TIMEOUT,为空时不限时
+	RequestTimeout time.Duration
+	//读取请求头的超时,默认取环境变量SU_READ_HEADER_TIMEOUT,为空时为10s
+	ReadHeaderTimeout time.Duration
+	//keep-alive连接的空闲超时,默认取环境变量SU_IDLE_TIMEOUT,为空时为120s
+	IdleTimeout time.Duration
+	//请求体大小限制,入口和路由未指定时使用,默认取环境变量SU_MAX_BODY_BYTES,如10MB,为空时不限制
+	MaxBodyBytes int64
+	//接收funclet的panic和错误,为nil时写入Logger
+	ErrorReporter ErrorReporter
+	//运行时使用的日志,默认按环境变量SU_LOG_FORMAT和SU_LOG_LEVEL创建,都为空时使用slog.Default()
+	Logger *slog.Logger
+	//是否记录访问日志,默认开启,环境变量SU_ACCESS_LOG为off时关闭
+	AccessLog bool
+	//在服务端口上提供Prometheus指标的路径,如/metrics,默认取环境变量SU_METRICS_PATH,为空时不提供
+	MetricsPath string
+	//单独提供Prometheus指标的监听地址,如127.0.0.1:9090,默认取环境变量SU_METRICS_ADDR,为空时不监听
+	MetricsAddr string
+	//导出trace的span,默认按环境变量SU_TRACE_EXPORTER(otlp|stdout|file)创建,为nil时只传递traceparent不导出
+	TraceExporter SpanExporter
+	//存活检查的路径,默认取环境变量SU_LIVE_PATH,为空时为/livez,为off时关闭
+	LivePath string
+	//就绪检查的路径,默认取环境变量SU_READY_PATH,为空时为/readyz,为off时关闭
+	ReadyPath string
+	//健康检查报告的路径,默认取环境变量SU_HEALTH_PATH,为空时为/healthz,为off时关闭
+	HealthPath string
+	//单次健康检查的超时,默认取环境变量SU_HEALTH_CHECK_TIMEOUT,为空时为5s
+	HealthCheckTimeout time.Duration
+	//输出路由表的调试路径,如/_faas/routes,默认取环境变量SU_DEBUG_PATH,为空时不提供
+	DebugPath string
+	//提供faasgen -openapi生成的OpenAPI文档的路径,如/openapi.json,默认取环境变量SU_OPENAPI_PATH,为空时不提供
+	OpenAPIPath string
+
+	entryMap       map[string]*Entry
+	gattHandlerMap map[string]func(http.ResponseWriter, *http.Request, *Context)
+	timings        []*timing
+	metrics        *metrics
+	tracer         *tracer
+	checks         []healthCheck
+	checksMu       sync.Mutex
+	ready          atomic.Bool
+	openAPI        openAPI
+	startHooks     []func(ctx context.Context) error
+	stopHooks      []func(ctx context.Context) error
+}
+
+func New() *App {
+	addr := os.Getenv("SU_SERVER_ADDR")
+	if addr == "" {
+		addr = ":8080"
+	}
+	a := &App{
+		Addr:               addr,
+		ShutdownTimeout:    envDuration("SU_SHUTDOWN_TIMEOUT", 10*time.Second),
+		RequestTimeout:     envDuration("SU_REQUEST_TIMEOUT", 0),
+		ReadHeaderTimeout:  envDuration("SU_READ_HEADER_TIMEOUT", 10*time.Second),
+		IdleTimeout:        envDuration("SU_IDLE_TIMEOUT", 120*time.Second),
+		MaxBodyBytes:       envSize("SU_MAX_BODY_BYTES", 0),
+		Logger:             newLogger(),
+		AccessLog:          os.Getenv("SU_ACCESS_LOG") != "off",
+		MetricsPath:        os.Getenv("SU_METRICS_PATH"),
+		MetricsAddr:        os.Getenv("SU_METRICS_ADDR"),
+		LivePath:           envPath("SU_LIVE_PATH", "/livez"),
+		ReadyPath:          envPath("SU_READY_PATH", "/readyz"),
+		HealthPath:         envPath("SU_HEALTH_PATH", "/healthz"),
+		HealthCheckTimeout: envDuration("SU_HEALTH_CHECK_TIMEOUT", 5*time.Second),
+		DebugPath:          os.Getenv("SU_DEBUG_PATH"),
+		OpenAPIPath:        os.Getenv("SU_OPENAPI_PATH"),
+		metrics:            newMetrics(),
+		entryMap:           make(map[string]*Entry),
+	}
+	a.TraceExporter = a.newTraceExporter()
+	a.tracer = newTracer(a)
+	return a
+}
+
+// envDuration 读取时长类型的环境变量,为空或格式错误时返回def
+func envDuration(name string, def time.Duration) time.Duration {
+	d, err := time.ParseDuration(os.Getenv(name))
+	if err != nil {
+		return def
+	}
+	return d
+}
+
+// envSize 读取大小类型的环境变量,为空或格式错误时返回def
+func envSize(name string, def int64) int64 {
+	n, err := ParseSize(os.Getenv(name))
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+var defaultApp = New()
+
+// Default 返回包级别函数使用的App
+func Default() *App {
+	return defaultApp
+}
+
+func Run() {
+	defaultApp.Run()
+}
+
+func RunContext(ctx context.Context) error {
+	return defaultApp.RunContext(ctx)
+}
+
+func OnStart(hook func(ctx context.Context) error) {
+	defaultApp.OnStart(hook)
+}
+
+func OnStop(hook func(ctx context.Context) error) {
+	defaultApp.OnStop(hook)
+}
+
+func HandleAuth(entryName string, handler func(http.ResponseWriter, *http.Request, *Context)) {
+	defaultApp.HandleAuth(entryName, handler)
+}
+
+func HandleAuthFunc(entryName string, handler AuthFunc) {
+	defaultApp.HandleAuthFunc(entryName, handler)
+}
+
+func SetOpenAPI(doc []byte) {
+	defaultApp.SetOpenAPI(doc)
+}
+
+func RegisterCheck(name string, check func(ctx context.Context) error) {
+	defaultApp.RegisterCheck(name, check)
+}
+
+func LimitBody(entryName string, n int64) {
+	defaultApp.LimitBody(entryName, n)
+}
+
+func HandleFunc(entryName, handlerType, path string, handler func(http.ResponseWriter, *http.Request), opts ...RouteOption) {
+	defaultApp.HandleFunc(entryName, handlerType, path, handler, opts...)
+}
+
+func Use(entryName string, mws ...Middleware) {
+	defaultApp.Use(entryName, mws...)
+}
+
+func UsePrefix(entryName, prefix string, mws ...Middleware) {
+	defaultApp.UsePrefix(entryName, prefix, mws...)
+}
+
+func UsePath(entryName, path string, mws ...Middleware) {
+	defaultApp.UsePath(entryName, path, mws...)
+}
+
+func TimingFunc(timingType, interval string, handler func(env map[string]any), opts ...TimingOption) {
+	defaultApp.TimingFunc(timingType, interval, handler, opts...)
+}
+
+func GattEntry(entryName, gattPath string, handler func(http.ResponseWriter, *http.Request, *Context), resDir string) {
+	defaultApp.GattEntry(entryName, gattPath, handler, resDir)
+}
+
+func RegisterGattFnHandler(fn string, handler func(http.ResponseWriter, *http.Request, *Context)) {
+	defaultApp.RegisterGattFnHandler(fn, handler)
+}
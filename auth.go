@@ -0,0 +1,100 @@
+package faas
+
+import (
+	"net/http"
+)
+
+// AuthFunc 返回错误的鉴权函数,返回nil放行,返回错误时按错误的状态码拒绝请求
+type AuthFunc func(http.ResponseWriter, *http.Request, *Context) error
+
+type authDecision int
+
+const (
+	authUndecided authDecision = iota
+	authAllowed
+	authDenied
+)
+
+// authFunc 将不返回错误的鉴权函数适配为AuthFunc
+func authFunc(handler func(http.ResponseWriter, *http.Request, *Context)) AuthFunc {
+	return func(w http.ResponseWriter, r *http.Request, c *Context) error {
+		handler(w, r, c)
+		return nil
+	}
+}
+
+// Allow 鉴权函数明确放行请求,即使已经写了响应头或状态码也继续执行后续的鉴权和handler
+func (c *Context) Allow() {
+	c.decision, c.denyErr = authAllowed, nil
+}
+
+// Deny 鉴权函数明确拒绝请求,鉴权函数返回后以status和message响应,已经写了响应时不再写
+func (c *Context) Deny(status int, message string) {
+	c.decision, c.denyErr = authDenied, &HTTPError{Status: status, Message: message}
+}
+
+// SetPrincipal 鉴权通过后设置调用者身份,同时写入Ctx以兼容读取Ctx的代码
+func (c *Context) SetPrincipal(principal any) {
+	c.principal = principal
+	c.Ctx = principal
+}
+
+// Principal 返回鉴权函数设置的调用者身份,未设置时返回Ctx
+func (c *Context) Principal() any {
+	if c.principal != nil {
+		return c.principal
+	}
+	return c.Ctx
+}
+
+// Principal 返回鉴权函数设置的类型为T的调用者身份,未设置或类型不符时ok为false
+func Principal[T any](c *Context) (T, bool) {
+	p, ok := c.Principal().(T)
+	return p, ok
+}
+
+// TypedAuth 将返回调用者身份的鉴权函数适配为AuthFunc,返回nil错误时以返回值调用SetPrincipal,
+// handler中用Principal[T]取出,faasgen在生成代码时检查两边的T一致
+func TypedAuth[T any](handler func(http.ResponseWriter, *http.Request, *Context) (T, error)) AuthFunc {
+	return func(w http.ResponseWriter, r *http.Request, c *Context) error {
+		p, err := handler(w, r, c)
+		if err == nil && c.decision != authDenied {
+			c.SetPrincipal(p)
+		}
+		return err
+	}
+}
+
+// authorize 依次执行鉴权链,返回false时请求已被拒绝并写了响应。
+// 鉴权函数返回错误或调用Deny时拒绝,调用Allow或返回nil时放行;
+// 既没有返回错误也没有调用Allow/Deny时,写了状态码视为拒绝,与旧的鉴权函数行为一致
+func (c *Context) authorize(r *http.Request, auths []AuthFunc) bool {
+	if len(auths) == 0 {
+		return true
+	}
+	// span只放在鉴权函数的请求副本上,handler使用调用方的请求
+	ar, span := startSpan(r, "auth")
+	defer span.End()
+	span.SetAttributes("faas.auth.count", len(auths))
+	for _, auth := range auths {
+		c.decision, c.denyErr = authUndecided, nil
+		err := auth(c.w, ar, c)
+		// 鉴权函数读取请求体后放回的是副本的Body(如auth.HMAC),同步回原请求
+		r.Body = ar.Body
+		if err == nil && c.decision == authDenied {
+			err = c.denyErr
+		}
+		if err != nil {
+			span.RecordError(err)
+			if c.w.Status() == 0 {
+				WriteError(c.w, r, err)
+			}
+			return false
+		}
+		if c.decision == authUndecided && c.w.Status() != 0 {
+			span.RecordError(&HTTPError{Status: c.w.Status()})
+			return false
+		}
+	}
+	return true
+}
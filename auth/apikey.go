@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/faasteam/faas"
+)
+
+// APIKeyConfig API Key鉴权的配置,Keys和KeysFile可以同时配置
+type APIKeyConfig struct {
+	//携带API Key的请求头,默认为X-API-Key,请求头不存在时也接受Authorization: ApiKey <key>
+	Header string
+	//API Key到调用者名称的映射
+	Keys map[string]string
+	//API Key文件,相对路径时位于DataDir下,每行为"key 名称",#开头为注释,文件修改后自动重新加载
+	KeysFile string
+}
+
+// APIKey 返回校验静态API Key的鉴权函数,校验通过后以{"sub": 名称}作为Claims写入Context
+func APIKey(cfg APIKeyConfig) faas.AuthFunc {
+	if len(cfg.Keys) == 0 && cfg.KeysFile == "" {
+		panic("auth: APIKey requires Keys or KeysFile")
+	}
+	header := cfg.Header
+	if header == "" {
+		header = "X-API-Key"
+	}
+	static := hashKeys(cfg.Keys)
+	var file *keyFile
+	if cfg.KeysFile != "" {
+		file = &keyFile{path: cfg.KeysFile}
+	}
+	return func(w http.ResponseWriter, r *http.Request, c *faas.Context) error {
+		key := r.Header.Get(header)
+		if key == "" {
+			key, _ = credentials(r, "ApiKey")
+		}
+		if key == "" {
+			return unauthorized(errMissingCredentials)
+		}
+		// 按摘要查找,比较的耗时与key的内容无关
+		sum := sha256.Sum256([]byte(key))
+		name, ok := static[sum]
+		if !ok && file != nil {
+			keys, err := file.load()
+			if err != nil {
+				return err
+			}
+			name, ok = keys[sum]
+		}
+		if !ok {
+			return unauthorized(errors.New("unknown api key"))
+		}
+		c.SetPrincipal(Claims{"sub": name})
+		return nil
+	}
+}
+
+func hashKeys(keys map[string]string) map[[sha256.Size]byte]string {
+	hashed := make(map[[sha256.Size]byte]string, len(keys))
+	for key, name := range keys {
+		hashed[sha256.Sum256([]byte(key))] = name
+	}
+	return hashed
+}
+
+// keyFile 本地的API Key文件,修改时间变化时重新加载
+type keyFile struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	keys    map[[sha256.Size]byte]string
+}
+
+func (kf *keyFile) load() (map[[sha256.Size]byte]string, error) {
+	path := dataPath(kf.path)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	kf.mu.Lock()
+	defer kf.mu.Unlock()
+	if kf.keys != nil && info.ModTime().Equal(kf.modTime) {
+		return kf.keys, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, name, _ := strings.Cut(line, " ")
+		keys[key] = strings.TrimSpace(name)
+	}
+	kf.keys, kf.modTime = hashKeys(keys), info.ModTime()
+	return kf.keys, nil
+}
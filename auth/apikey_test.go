@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAPIKey(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "keys")
+	if err := os.WriteFile(file, []byte("# keys\nfile-key bob\n\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fn := APIKey(APIKeyConfig{Keys: map[string]string{"static-key": "alice"}, KeysFile: file})
+	tests := []struct {
+		header, value string
+		status        int
+		sub           string
+	}{
+		{"X-API-Key", "static-key", http.StatusOK, "alice"},
+		{"X-API-Key", "file-key", http.StatusOK, "bob"},
+		{"Authorization", "ApiKey static-key", http.StatusOK, "alice"},
+		{"Authorization", "apikey static-key", http.StatusOK, "alice"},
+		{"Authorization", "Bearer static-key", http.StatusUnauthorized, ""},
+		{"X-API-Key", "static-key2", http.StatusUnauthorized, ""},
+		{"X-API-Key", "# keys", http.StatusUnauthorized, ""},
+		{"", "", http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.header != "" {
+			r.Header.Set(tt.header, tt.value)
+		}
+		w := serveAuth(fn, r)
+		if w.Code != tt.status || (tt.status == http.StatusOK && w.Body.String() != tt.sub) {
+			t.Errorf("%s: %q: got %d %q, want %d %q", tt.header, tt.value, w.Code, w.Body.String(), tt.status, tt.sub)
+		}
+	}
+
+	// 文件修改后重新加载
+	if err := os.WriteFile(file, []byte("new-key carol\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(file, later, later)
+	for key, want := range map[string]int{"new-key": http.StatusOK, "file-key": http.StatusUnauthorized} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("X-API-Key", key)
+		if w := serveAuth(fn, r); w.Code != want {
+			t.Errorf("after reload %q: status = %d, want %d", key, w.Code, want)
+		}
+	}
+}
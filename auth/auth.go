@@ -0,0 +1,89 @@
+// Package auth 提供内置的鉴权函数:JWT、API Key和HMAC请求签名,
+// 返回的faas.AuthFunc可直接用于HandleAuthFunc、WithAuthFunc或在@onAuthFunclet中调用,
+// 鉴权通过后以Claims作为调用者身份写入Context
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/faasteam/faas"
+)
+
+// Claims 鉴权通过后的调用者信息,JWT为令牌的payload,API Key和HMAC为{"sub": 名称}
+type Claims map[string]any
+
+// Subject 返回sub声明
+func (c Claims) Subject() string {
+	s, _ := c["sub"].(string)
+	return s
+}
+
+// Issuer 返回iss声明
+func (c Claims) Issuer() string {
+	s, _ := c["iss"].(string)
+	return s
+}
+
+// Audience 返回aud声明,aud为字符串时返回只有一个元素的切片
+func (c Claims) Audience() []string {
+	switch v := c["aud"].(type) {
+	case string:
+		return []string{v}
+	case []any:
+		aud := make([]string, 0, len(v))
+		for _, a := range v {
+			if s, ok := a.(string); ok {
+				aud = append(aud, s)
+			}
+		}
+		return aud
+	}
+	return nil
+}
+
+// time 返回数字类型的时间声明,如exp、nbf、iat,声明不存在时ok为false,不是数字时返回错误
+func (c Claims) time(name string) (t time.Time, ok bool, err error) {
+	v, ok := c[name]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	sec, isNumber := v.(float64)
+	if !isNumber {
+		return time.Time{}, false, errors.New("bad token " + name + " claim")
+	}
+	return time.Unix(int64(sec), 0), true, nil
+}
+
+// FromContext 返回auth包的鉴权函数设置的Claims
+func FromContext(c *faas.Context) (Claims, bool) {
+	claims, ok := c.Principal().(Claims)
+	return claims, ok
+}
+
+var errMissingCredentials = errors.New("missing credentials")
+
+// unauthorized 返回401错误,不向客户端暴露具体原因
+func unauthorized(err error) error {
+	return &faas.HTTPError{Status: http.StatusUnauthorized, Err: err}
+}
+
+// credentials 返回Authorization请求头中scheme对应的凭证,scheme不区分大小写
+func credentials(r *http.Request, scheme string) (string, bool) {
+	s, cred, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(s, scheme) {
+		return "", false
+	}
+	return strings.TrimSpace(cred), true
+}
+
+// dataPath 相对路径的文件位于沙箱数据目录下
+func dataPath(name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(faas.FAAS.DataDir, name)
+}
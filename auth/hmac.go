@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/faasteam/faas"
+)
+
+// HMAC签名使用的请求头
+const (
+	SignatureKeyHeader       = "X-Signature-Key"
+	SignatureTimestampHeader = "X-Signature-Timestamp"
+	SignatureHeader          = "X-Signature"
+)
+
+// HMACConfig HMAC请求签名鉴权的配置
+type HMACConfig struct {
+	//密钥ID到密钥的映射,密钥ID同时作为调用者名称
+	Keys map[string][]byte
+	//允许的时间戳误差,默认5分钟,超出时拒绝以限制重放
+	MaxSkew time.Duration
+	//参与签名的请求体的最大长度,默认10MB,超出时响应413
+	MaxBodyBytes int64
+}
+
+// HMAC 返回校验请求签名的鉴权函数,签名为对StringToSign的HMAC-SHA256的十六进制编码,
+// 校验通过后以{"sub": 密钥ID}作为Claims写入Context,请求体读取后会重新放回供handler使用
+func HMAC(cfg HMACConfig) faas.AuthFunc {
+	if len(cfg.Keys) == 0 {
+		panic("auth: HMAC requires Keys")
+	}
+	if cfg.MaxSkew <= 0 {
+		cfg.MaxSkew = 5 * time.Minute
+	}
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = 10 << 20
+	}
+	return func(w http.ResponseWriter, r *http.Request, c *faas.Context) error {
+		keyID := r.Header.Get(SignatureKeyHeader)
+		ts := r.Header.Get(SignatureTimestampHeader)
+		sig, err := hex.DecodeString(r.Header.Get(SignatureHeader))
+		if keyID == "" || ts == "" || err != nil || len(sig) == 0 {
+			return unauthorized(errMissingCredentials)
+		}
+		secret, ok := cfg.Keys[keyID]
+		if !ok {
+			return unauthorized(errors.New("unknown signature key " + keyID))
+		}
+		sec, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			return unauthorized(errors.New("bad signature timestamp"))
+		}
+		if skew := time.Since(time.Unix(sec, 0)); skew > cfg.MaxSkew || skew < -cfg.MaxSkew {
+			return unauthorized(errors.New("signature timestamp out of range"))
+		}
+		body, err := readBody(r, cfg.MaxBodyBytes)
+		if err != nil {
+			return err
+		}
+		if !hmac.Equal(sign(secret, StringToSign(r.Method, r.URL.RequestURI(), ts, body)), sig) {
+			return unauthorized(errors.New("bad signature"))
+		}
+		c.SetPrincipal(Claims{"sub": keyID})
+		return nil
+	}
+}
+
+// StringToSign 返回参与签名的内容:方法、RequestURI、时间戳和请求体SHA-256的十六进制编码,以换行分隔
+func StringToSign(method, requestURI, timestamp string, body []byte) string {
+	sum := sha256.Sum256(body)
+	return method + "\n" + requestURI + "\n" + timestamp + "\n" + hex.EncodeToString(sum[:])
+}
+
+// Sign 为客户端请求设置签名请求头,请求体会被读取后重新放回
+func Sign(r *http.Request, keyID string, secret []byte) error {
+	var body []byte
+	if r.Body != nil {
+		var err error
+		body, err = io.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			return err
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	r.Header.Set(SignatureKeyHeader, keyID)
+	r.Header.Set(SignatureTimestampHeader, ts)
+	r.Header.Set(SignatureHeader, hex.EncodeToString(sign(secret, StringToSign(r.Method, r.URL.RequestURI(), ts, body))))
+	return nil
+}
+
+func sign(secret []byte, s string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(s))
+	return mac.Sum(nil)
+}
+
+// readBody 读取请求体并重新放回
+func readBody(r *http.Request, limit int64) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, limit+1))
+	if err != nil {
+		return nil, &faas.HTTPError{Status: http.StatusBadRequest, Err: err}
+	}
+	if int64(len(body)) > limit {
+		return nil, &faas.HTTPError{Status: http.StatusRequestEntityTooLarge}
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/faasteam/faas"
+)
+
+func TestHMAC(t *testing.T) {
+	secret := []byte("secret")
+	app := faas.New()
+	app.HandleAuthFunc("api", HMAC(HMACConfig{Keys: map[string][]byte{"client": secret}, MaxBodyBytes: 16}))
+	app.HandleFunc("api", "path", "/echo", faas.WithContextHandler(func(w http.ResponseWriter, r *http.Request, c *faas.Context) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("read body: %v", err)
+		}
+		claims, _ := FromContext(c)
+		w.Write([]byte(claims.Subject() + ":" + string(body)))
+	}))
+
+	tests := []struct {
+		name   string
+		body   string
+		modify func(r *http.Request)
+		status int
+	}{
+		{"body passthrough", "hello", nil, http.StatusOK},
+		{"empty body", "", nil, http.StatusOK},
+		{"tampered body", "hello", func(r *http.Request) {
+			r.Body = io.NopCloser(strings.NewReader("hellO"))
+		}, http.StatusUnauthorized},
+		{"tampered query", "hello", func(r *http.Request) {
+			r.URL.RawQuery = "admin=1"
+		}, http.StatusUnauthorized},
+		{"wrong secret", "hello", func(r *http.Request) {
+			Sign(r, "client", []byte("other"))
+		}, http.StatusUnauthorized},
+		{"unknown key", "hello", func(r *http.Request) {
+			r.Header.Set(SignatureKeyHeader, "other")
+		}, http.StatusUnauthorized},
+		{"old timestamp", "hello", func(r *http.Request) {
+			ts := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+			r.Header.Set(SignatureTimestampHeader, ts)
+			r.Header.Set(SignatureHeader, hex.EncodeToString(sign(secret, StringToSign(r.Method, r.URL.RequestURI(), ts, []byte("hello")))))
+		}, http.StatusUnauthorized},
+		{"bad signature encoding", "hello", func(r *http.Request) {
+			r.Header.Set(SignatureHeader, "zz")
+		}, http.StatusUnauthorized},
+		{"missing signature", "hello", func(r *http.Request) {
+			r.Header.Del(SignatureHeader)
+		}, http.StatusUnauthorized},
+		{"body too large", strings.Repeat("x", 17), nil, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(tt.body))
+			if err := Sign(r, "client", secret); err != nil {
+				t.Fatal(err)
+			}
+			if tt.modify != nil {
+				tt.modify(r)
+			}
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, r)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			if tt.status == http.StatusOK && w.Body.String() != "client:"+tt.body {
+				t.Errorf("body = %q, want %q", w.Body.String(), "client:"+tt.body)
+			}
+		})
+	}
+}
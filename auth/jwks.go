@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+)
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+type publicKey struct {
+	kid string
+	alg string
+	key any
+}
+
+// keySet 本地JWKS文件中的密钥,文件的修改时间变化时重新加载,便于轮换密钥
+type keySet struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	keys    []publicKey
+}
+
+func (ks *keySet) lookup(alg, kid string) (any, error) {
+	keys, err := ks.load()
+	if err != nil {
+		return nil, err
+	}
+	var found any
+	for _, k := range keys {
+		if k.alg != alg {
+			continue
+		}
+		if kid != "" && k.kid == kid {
+			return k.key, nil
+		}
+		if kid == "" {
+			if found != nil {
+				return nil, errors.New("token has no kid and jwks has multiple " + alg + " keys")
+			}
+			found = k.key
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no %s key for kid %q", alg, kid)
+	}
+	return found, nil
+}
+
+func (ks *keySet) load() ([]publicKey, error) {
+	path := dataPath(ks.path)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if ks.keys != nil && info.ModTime().Equal(ks.modTime) {
+		return ks.keys, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return nil, fmt.Errorf("bad jwks %s: %w", path, err)
+	}
+	ks.keys, ks.modTime = keys, info.ModTime()
+	return keys, nil
+}
+
+func parseJWKS(data []byte) ([]publicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+	keys := make([]publicKey, 0, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pk, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", k.Kid, err)
+		}
+		keys = append(keys, pk)
+	}
+	return keys, nil
+}
+
+func (k *jwk) publicKey() (publicKey, error) {
+	pk := publicKey{kid: k.Kid, alg: k.Alg}
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return pk, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return pk, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return pk, errors.New("bad rsa exponent")
+		}
+		pk.key = &rsa.PublicKey{N: n, E: int(e.Int64())}
+		if pk.alg == "" {
+			pk.alg = "RS256"
+		}
+	case "EC":
+		if k.Crv != "P-256" {
+			return pk, errors.New("unsupported curve " + k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return pk, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return pk, err
+		}
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return pk, errors.New("point is not on curve")
+		}
+		pk.key = &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		if pk.alg == "" {
+			pk.alg = "ES256"
+		}
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil {
+			return pk, err
+		}
+		pk.key = secret
+		if pk.alg == "" {
+			pk.alg = "HS256"
+		}
+	default:
+		return pk, errors.New("unsupported kty " + k.Kty)
+	}
+	return pk, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/faasteam/faas"
+)
+
+// JWTConfig JWT鉴权的配置,Secret和JWKSFile至少配置一个
+type JWTConfig struct {
+	//HS256的密钥
+	Secret []byte
+	//JWKS文件,相对路径时位于DataDir下,文件修改后自动重新加载,支持RSA(RS256)、EC P-256(ES256)和oct(HS256)密钥
+	JWKSFile string
+	//不为空时校验iss
+	Issuer string
+	//不为空时校验aud包含Audience
+	Audience string
+	//校验exp和nbf时允许的时钟误差
+	Leeway time.Duration
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// JWT 返回校验Authorization: Bearer令牌的鉴权函数,支持HS256、RS256和ES256,
+// 校验通过后令牌的payload作为Claims写入Context
+func JWT(cfg JWTConfig) faas.AuthFunc {
+	if len(cfg.Secret) == 0 && cfg.JWKSFile == "" {
+		panic("auth: JWT requires Secret or JWKSFile")
+	}
+	var keys *keySet
+	if cfg.JWKSFile != "" {
+		keys = &keySet{path: cfg.JWKSFile}
+	}
+	return func(w http.ResponseWriter, r *http.Request, c *faas.Context) error {
+		token, ok := credentials(r, "Bearer")
+		if !ok || token == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer`)
+			return unauthorized(errMissingCredentials)
+		}
+		claims, err := cfg.verify(token, keys, time.Now())
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			return unauthorized(err)
+		}
+		c.SetPrincipal(claims)
+		return nil
+	}
+}
+
+func (cfg *JWTConfig) verify(token string, keys *keySet, now time.Time) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("bad token header: %w", err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("bad token signature: %w", err)
+	}
+	key, err := cfg.key(header, keys)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, err
+	}
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("bad token payload: %w", err)
+	}
+	exp, ok, err := claims.time("exp")
+	if err != nil {
+		return nil, err
+	}
+	if ok && !now.Before(exp.Add(cfg.Leeway)) {
+		return nil, errors.New("token expired")
+	}
+	nbf, ok, err := claims.time("nbf")
+	if err != nil {
+		return nil, err
+	}
+	if ok && now.Add(cfg.Leeway).Before(nbf) {
+		return nil, errors.New("token not valid yet")
+	}
+	if cfg.Issuer != "" && claims.Issuer() != cfg.Issuer {
+		return nil, errors.New("bad token issuer")
+	}
+	if cfg.Audience != "" && !contains(claims.Audience(), cfg.Audience) {
+		return nil, errors.New("bad token audience")
+	}
+	return claims, nil
+}
+
+// key 返回校验签名使用的密钥,JWKS中按kid查找,没有kid时使用唯一一个alg匹配的密钥
+func (cfg *JWTConfig) key(header jwtHeader, keys *keySet) (any, error) {
+	switch header.Alg {
+	case "HS256", "RS256", "ES256":
+	default:
+		return nil, fmt.Errorf("unsupported alg %q", header.Alg)
+	}
+	if header.Alg == "HS256" && len(cfg.Secret) != 0 && (header.Kid == "" || keys == nil) {
+		return cfg.Secret, nil
+	}
+	if keys == nil {
+		return nil, fmt.Errorf("no key for alg %s", header.Alg)
+	}
+	return keys.lookup(header.Alg, header.Kid)
+}
+
+func verifySignature(alg string, key any, signed string, sig []byte) error {
+	switch alg {
+	case "HS256":
+		secret, ok := key.([]byte)
+		if !ok {
+			return errors.New("key type does not match alg " + alg)
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(mac.Sum(nil), sig) {
+			return errors.New("bad token signature")
+		}
+	case "RS256":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("key type does not match alg " + alg)
+		}
+		sum := sha256.Sum256([]byte(signed))
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, sum[:], sig); err != nil {
+			return errors.New("bad token signature")
+		}
+	case "ES256":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(sig) != 64 {
+			return errors.New("bad token signature")
+		}
+		sum := sha256.Sum256([]byte(signed))
+		r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(pub, sum[:], r, s) {
+			return errors.New("bad token signature")
+		}
+	}
+	return nil
+}
+
+func decodeSegment(seg string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,231 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/faasteam/faas"
+)
+
+var (
+	hsSecret = []byte("hs256-secret")
+	rsaKey   *rsa.PrivateKey
+	ecKey    *ecdsa.PrivateKey
+)
+
+func init() {
+	var err error
+	if rsaKey, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+		panic(err)
+	}
+	if ecKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+		panic(err)
+	}
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func b64JSON(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return b64(data)
+}
+
+// signToken 按header中的alg签名,kid不为空时写入header
+func signToken(alg, kid string, claims map[string]any) string {
+	header := map[string]any{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	signed := b64JSON(header) + "." + b64JSON(claims)
+	sum := sha256.Sum256([]byte(signed))
+	var sig []byte
+	switch alg {
+	case "HS256":
+		mac := hmac.New(sha256.New, hsSecret)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case "RS256":
+		var err error
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, sum[:]); err != nil {
+			panic(err)
+		}
+	case "ES256":
+		r, s, err := ecdsa.Sign(rand.Reader, ecKey, sum[:])
+		if err != nil {
+			panic(err)
+		}
+		sig = make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+	}
+	return signed + "." + b64(sig)
+}
+
+// writeJWKS 将测试用的RSA和EC公钥写入JWKS文件
+func writeJWKS(t *testing.T) string {
+	t.Helper()
+	ecX, ecY := make([]byte, 32), make([]byte, 32)
+	ecKey.PublicKey.X.FillBytes(ecX)
+	ecKey.PublicKey.Y.FillBytes(ecY)
+	set := map[string]any{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa1", "use": "sig", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+		{"kty": "EC", "kid": "ec1", "crv": "P-256", "x": b64(ecX), "y": b64(ecY)},
+		{"kty": "RSA", "kid": "enc", "use": "enc", "n": "AQAB", "e": "AQAB"},
+	}}
+	data, _ := json.Marshal(set)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestJWTVerify(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	cfg := &JWTConfig{Secret: hsSecret, Issuer: "issuer", Audience: "api", Leeway: 30 * time.Second}
+	keys := &keySet{path: writeJWKS(t)}
+	valid := func(extra map[string]any) map[string]any {
+		claims := map[string]any{"sub": "alice", "iss": "issuer", "aud": "api", "exp": now.Add(time.Hour).Unix()}
+		for k, v := range extra {
+			if v == nil {
+				delete(claims, k)
+			} else {
+				claims[k] = v
+			}
+		}
+		return claims
+	}
+	hs := signToken("HS256", "", valid(nil))
+	es := signToken("ES256", "ec1", valid(nil))
+	noneToken := b64JSON(map[string]string{"alg": "none"}) + "." + b64JSON(valid(nil)) + "."
+	// 以RSA公钥作为HS256密钥签名的令牌不能通过校验
+	confused := func() string {
+		header := b64JSON(map[string]string{"alg": "HS256", "kid": "rsa1"})
+		signed := header + "." + b64JSON(valid(nil))
+		mac := hmac.New(sha256.New, rsaKey.N.Bytes())
+		mac.Write([]byte(signed))
+		return signed + "." + b64(mac.Sum(nil))
+	}()
+
+	tests := []struct {
+		name  string
+		token string
+		err   string
+	}{
+		{"hs256", hs, ""},
+		{"rs256 jwks", signToken("RS256", "rsa1", valid(nil)), ""},
+		{"es256 jwks", es, ""},
+		{"es256 without kid", signToken("ES256", "", valid(nil)), ""},
+		{"audience list", signToken("HS256", "", valid(map[string]any{"aud": []string{"web", "api"}})), ""},
+		{"no exp", signToken("HS256", "", valid(map[string]any{"exp": nil})), ""},
+		{"expired within leeway", signToken("HS256", "", valid(map[string]any{"exp": now.Add(-10 * time.Second).Unix()})), ""},
+		{"expired", signToken("HS256", "", valid(map[string]any{"exp": now.Add(-time.Minute).Unix()})), "token expired"},
+		{"not valid yet", signToken("HS256", "", valid(map[string]any{"nbf": now.Add(time.Minute).Unix()})), "token not valid yet"},
+		{"nbf within leeway", signToken("HS256", "", valid(map[string]any{"nbf": now.Add(10 * time.Second).Unix()})), ""},
+		{"string exp", signToken("HS256", "", valid(map[string]any{"exp": "never"})), "bad token exp claim"},
+		{"bool nbf", signToken("HS256", "", valid(map[string]any{"nbf": true})), "bad token nbf claim"},
+		{"bad issuer", signToken("HS256", "", valid(map[string]any{"iss": "other"})), "bad token issuer"},
+		{"bad audience", signToken("HS256", "", valid(map[string]any{"aud": "web"})), "bad token audience"},
+		{"alg none", noneToken, `unsupported alg "none"`},
+		{"alg hs512", strings.Replace(hs, hs[:strings.Index(hs, ".")], b64JSON(map[string]string{"alg": "HS512"}), 1), `unsupported alg "HS512"`},
+		{"alg confusion", confused, "no HS256 key for kid \"rsa1\""},
+		{"unknown kid", signToken("RS256", "rsa2", valid(nil)), `no RS256 key for kid "rsa2"`},
+		{"encryption key", signToken("RS256", "enc", valid(nil)), `no RS256 key for kid "enc"`},
+		{"bad signature", hs[:len(hs)-4] + "AAAA", "bad token signature"},
+		{"tampered payload", strings.Replace(hs, strings.Split(hs, ".")[1], b64JSON(valid(map[string]any{"sub": "mallory"})), 1), "bad token signature"},
+		{"es256 bad signature length", es[:strings.LastIndex(es, ".")+1] + b64([]byte("short")), "bad token signature"},
+		{"two segments", "a.b", "malformed token"},
+		{"bad header", "!!!." + strings.SplitN(hs, ".", 2)[1], "bad token header"},
+		{"bad payload", b64JSON(map[string]string{"alg": "HS256"}) + ".e30.", "bad token signature"},
+		{"payload not json", func() string {
+			signed := b64JSON(map[string]string{"alg": "HS256"}) + "." + b64([]byte("not json"))
+			mac := hmac.New(sha256.New, hsSecret)
+			mac.Write([]byte(signed))
+			return signed + "." + b64(mac.Sum(nil))
+		}(), "bad token payload"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := cfg.verify(tt.token, keys, now)
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("verify: %v", err)
+				}
+				if claims.Subject() != "alice" {
+					t.Errorf("sub = %q, want alice", claims.Subject())
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("verify error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+// serveAuth 以fn作为入口的鉴权链处理请求,handler响应调用者的sub
+func serveAuth(fn faas.AuthFunc, r *http.Request) *httptest.ResponseRecorder {
+	app := faas.New()
+	app.HandleAuthFunc("api", fn)
+	app.HandleFunc("api", "path", "/", faas.WithContextHandler(func(w http.ResponseWriter, r *http.Request, c *faas.Context) {
+		claims, _ := FromContext(c)
+		w.Write([]byte(claims.Subject()))
+	}))
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, r)
+	return w
+}
+
+func TestJWT(t *testing.T) {
+	fn := JWT(JWTConfig{Secret: hsSecret})
+	token := signToken("HS256", "", map[string]any{"sub": "alice", "exp": time.Now().Add(time.Hour).Unix()})
+	tests := []struct {
+		authorization string
+		status        int
+		challenge     string
+	}{
+		{"Bearer " + token, http.StatusOK, ""},
+		{"bearer " + token, http.StatusOK, ""},
+		{"BEARER  " + token, http.StatusOK, ""},
+		{"", http.StatusUnauthorized, "Bearer"},
+		{"Bearer", http.StatusUnauthorized, "Bearer"},
+		{"Basic " + token, http.StatusUnauthorized, "Bearer"},
+		{"Bearer " + token + "x", http.StatusUnauthorized, `Bearer error="invalid_token"`},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.authorization != "" {
+			r.Header.Set("Authorization", tt.authorization)
+		}
+		w := serveAuth(fn, r)
+		if w.Code != tt.status {
+			t.Errorf("Authorization %q: status = %d, want %d", tt.authorization, w.Code, tt.status)
+		}
+		if tt.status == http.StatusOK && w.Body.String() != "alice" {
+			t.Errorf("Authorization %q: body = %q, want alice", tt.authorization, w.Body.String())
+		}
+		if got := w.Header().Get("WWW-Authenticate"); got != tt.challenge {
+			t.Errorf("Authorization %q: WWW-Authenticate = %q, want %q", tt.authorization, got, tt.challenge)
+		}
+	}
+}
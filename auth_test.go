@@ -0,0 +1,166 @@
+package faas
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAuthChain(t *testing.T) {
+	var trace []string
+	// X-Deny为鉴权函数名时该鉴权函数拒绝请求
+	denied := func(r *http.Request, name string) bool {
+		trace = append(trace, name)
+		return r.Header.Get("X-Deny") == name
+	}
+	app := New()
+	app.HandleAuth("api", func(w http.ResponseWriter, r *http.Request, c *Context) {
+		if denied(r, "first") {
+			c.Deny(http.StatusForbidden, "no")
+		}
+	})
+	app.HandleAuthFunc("api", func(w http.ResponseWriter, r *http.Request, c *Context) error {
+		if denied(r, "second") {
+			return ErrUnauthorized
+		}
+		return nil
+	})
+	// 没有调用Allow/Deny而写了状态码视为拒绝
+	app.HandleAuth("api", func(w http.ResponseWriter, r *http.Request, c *Context) {
+		if denied(r, "third") {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	})
+	custom := func(w http.ResponseWriter, r *http.Request, c *Context) {
+		if denied(r, "custom") {
+			c.Deny(http.StatusForbidden, "custom")
+		}
+	}
+	customFunc := func(w http.ResponseWriter, r *http.Request, c *Context) error {
+		trace = append(trace, "customFunc")
+		return nil
+	}
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		trace = append(trace, "handler")
+	}
+	app.HandleFunc("api", "path", "/private", handler)
+	app.HandleFunc("api", "path", "/open", handler, NoAuth())
+	app.HandleFunc("api", "path", "/custom", handler, WithAuth(custom))
+	app.HandleFunc("api", "path", "/mixed", handler, WithAuth(custom), WithAuthFunc(customFunc))
+	app.HandleFunc("api", "path", "/users/{id}", handler, Methods("GET"), NoAuth())
+	app.HandleFunc("api", "path", "/users/{id}", handler, Methods("POST"))
+	app.HandleFunc("api", "prefix", "/files/{bucket}", handler, NoAuth())
+	app.HandleFunc("api", "prefix", "/static", handler, NoAuth())
+
+	entry := "first second third"
+	tests := []struct {
+		method, path, deny string
+		status             int
+		trace              string
+	}{
+		{"GET", "/private", "", 200, entry + " handler"},
+		{"GET", "/private", "first", 403, "first"},
+		{"GET", "/private", "second", 401, "first second"},
+		{"GET", "/private", "third", 429, entry},
+		{"GET", "/open", "first", 200, "handler"},
+		{"GET", "/custom", "first", 200, "custom handler"},
+		{"GET", "/custom", "custom", 403, "custom"},
+		{"GET", "/mixed", "", 200, "custom customFunc handler"},
+		// 按方法覆盖
+		{"GET", "/users/1", "first", 200, "handler"},
+		{"POST", "/users/1", "first", 403, "first"},
+		{"GET", "/files/b1/a.txt", "first", 200, "handler"},
+		// ServeMux补全末尾/的重定向不使用目标路由的配置
+		{"GET", "/files/b1", "first", 403, "first"},
+		{"GET", "/static", "first", 403, "first"},
+		{"GET", "/static/a.css", "first", 200, "handler"},
+		// 没有匹配的路由使用入口的鉴权链
+		{"GET", "/missing", "first", 403, "first"},
+	}
+	for _, tt := range tests {
+		trace = nil
+		r := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.deny != "" {
+			r.Header.Set("X-Deny", tt.deny)
+		}
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, r)
+		if got := strings.Join(trace, " "); w.Code != tt.status || got != tt.trace {
+			t.Errorf("%s %s deny %q = %d [%s], want %d [%s]", tt.method, tt.path, tt.deny, w.Code, got, tt.status, tt.trace)
+		}
+	}
+
+	trace = nil
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/files/b1", nil))
+	if got := strings.Join(trace, " "); w.Code/100 != 3 || w.Header().Get("Location") != "/files/b1/" || got != entry {
+		t.Errorf("GET /files/b1 = %d Location %q [%s], want a redirect to /files/b1/ after [%s]", w.Code, w.Header().Get("Location"), got, entry)
+	}
+}
+
+type testUser struct {
+	Name string
+}
+
+func TestTypedAuth(t *testing.T) {
+	app := New()
+	app.HandleAuthFunc("api", TypedAuth(func(w http.ResponseWriter, r *http.Request, c *Context) (*testUser, error) {
+		switch r.Header.Get("X-User") {
+		case "":
+			return nil, ErrUnauthorized
+		case "banned":
+			// 调用Deny时不设置返回的身份
+			c.Deny(http.StatusForbidden, "banned")
+		}
+		return &testUser{Name: r.Header.Get("X-User")}, nil
+	}))
+	app.HandleAuth("local", func(w http.ResponseWriter, r *http.Request, c *Context) {
+		c.Ctx = "legacy"
+	})
+	var principal any
+	handler := WithContextHandler(func(w http.ResponseWriter, r *http.Request, c *Context) {
+		principal = c.Principal()
+		if u, ok := Principal[*testUser](c); ok {
+			w.Write([]byte("user " + u.Name))
+		}
+		if _, ok := Principal[testUser](c); ok {
+			t.Error("Principal[testUser] matched a *testUser")
+		}
+		if s, ok := Principal[string](c); ok {
+			w.Write([]byte("string " + s))
+		}
+	})
+	app.HandleFunc("api", "path", "/me", handler)
+	app.HandleFunc("api", "path", "/public", handler, NoAuth())
+	app.HandleFunc("local", "path", "/me", handler)
+
+	tests := []struct {
+		entry, path, user string
+		status            int
+		body              string
+	}{
+		{"api", "/me", "alice", 200, "user alice"},
+		{"api", "/me", "", 401, "Unauthorized\n"},
+		{"api", "/me", "banned", 403, "banned\n"},
+		{"api", "/public", "alice", 200, ""},
+		// 旧的鉴权函数写入Ctx,Principal同样可以取到
+		{"local", "/me", "", 200, "string legacy"},
+	}
+	for _, tt := range tests {
+		principal = nil
+		r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		r.Header.Set("Faas-Gateway-Name", tt.entry)
+		if tt.user != "" {
+			r.Header.Set("X-User", tt.user)
+		}
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, r)
+		if w.Code != tt.status || w.Body.String() != tt.body {
+			t.Errorf("%s %s as %q = %d %q, want %d %q", tt.entry, tt.path, tt.user, w.Code, w.Body.String(), tt.status, tt.body)
+		}
+		if tt.path == "/public" && principal != nil {
+			t.Errorf("NoAuth route has principal %v", principal)
+		}
+	}
+}
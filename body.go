@@ -0,0 +1,130 @@
+package faas
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// MaxBodyBytes 限制路由的请求体大小,超出时响应413,为0时使用入口或App的配置,小于0时不限制
+func MaxBodyBytes(n int64) RouteOption {
+	return func(o *routeOptions) {
+		o.maxBodyBytes = n
+	}
+}
+
+// ContentTypes 限制路由接受的请求体类型,如application/json、text/*,
+// 带请求体且Content-Type不在其中时响应415
+func ContentTypes(types ...string) RouteOption {
+	return func(o *routeOptions) {
+		for _, t := range types {
+			o.contentTypes = append(o.contentTypes, strings.ToLower(strings.TrimSpace(t)))
+		}
+	}
+}
+
+// LimitBody 设置入口的请求体大小限制,路由未通过MaxBodyBytes指定时使用,为0时使用App.MaxBodyBytes,小于0时不限制
+func (a *App) LimitBody(entryName string, n int64) {
+	a.logger().Info("registering body limit", "entry", entryName, "bytes", n)
+	a.entry(entryName).maxBodyBytes = n
+}
+
+// bodyLimit 返回路由生效的请求体大小限制,0表示不限制
+func (rt *route) bodyLimit(h *routeHandler) int64 {
+	for _, n := range []int64{h.maxBodyBytes, rt.entry.maxBodyBytes, rt.entry.app.MaxBodyBytes} {
+		if n < 0 {
+			return 0
+		}
+		if n > 0 {
+			return n
+		}
+	}
+	return 0
+}
+
+// checkBody 在handler执行前检查请求体的类型和大小,不符合时写响应并返回false
+func (rt *route) checkBody(w http.ResponseWriter, r *http.Request, h *routeHandler) bool {
+	if len(h.contentTypes) != 0 && hasBody(r) && !acceptContentType(h.contentTypes, r.Header.Get("Content-Type")) {
+		accepted := strings.Join(h.contentTypes, ", ")
+		// Accept是请求头,响应中POST用Accept-Post、PATCH用Accept-Patch告知接受的类型,同时写在响应内容中
+		switch r.Method {
+		case http.MethodPost:
+			w.Header().Set("Accept-Post", accepted)
+		case http.MethodPatch:
+			w.Header().Set("Accept-Patch", accepted)
+		}
+		WriteError(w, r, &HTTPError{Status: http.StatusUnsupportedMediaType, Message: "Unsupported Media Type, accepted: " + accepted})
+		return false
+	}
+	if n := rt.bodyLimit(h); n > 0 && r.Body != nil {
+		if r.ContentLength > n {
+			WriteError(w, r, &HTTPError{Status: http.StatusRequestEntityTooLarge})
+			return false
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, n)
+	}
+	return true
+}
+
+func hasBody(r *http.Request) bool {
+	return r.ContentLength > 0 || (r.ContentLength < 0 && r.Body != nil && r.Body != http.NoBody)
+}
+
+// acceptContentType 判断Content-Type是否在允许的类型中,支持text/*形式的通配
+func acceptContentType(types []string, contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, t := range types {
+		if t == mediaType || t == "*/*" {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(t, "/*"); ok && strings.HasPrefix(mediaType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// readBody 读取请求体,超出大小限制时返回413错误,其他读取错误返回400错误
+func readBody(r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, bodyError(err)
+	}
+	return body, nil
+}
+
+// bodyError 将读取请求体的错误转换为HTTPError
+func bodyError(err error) error {
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		return &HTTPError{Status: http.StatusRequestEntityTooLarge, Err: err}
+	}
+	return &HTTPError{Status: http.StatusBadRequest, Err: err}
+}
+
+// ParseSize 解析1048576、512KB、10MB、1GB形式的大小,单位为1024进制,不区分大小写
+func ParseSize(size string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(size))
+	mult := int64(1)
+	for _, u := range []struct {
+		suffix string
+		mult   int64
+	}{{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30}, {"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"B", 1}} {
+		if v, ok := strings.CutSuffix(s, u.suffix); ok {
+			s, mult = strings.TrimSpace(v), u.mult
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 || n > (1<<63-1)/mult {
+		return 0, fmt.Errorf("bad size %q", size)
+	}
+	return n * mult, nil
+}
@@ -0,0 +1,114 @@
+package faas
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+		ok   bool
+	}{
+		{"1048576", 1048576, true},
+		{"512KB", 512 << 10, true},
+		{"10mb", 10 << 20, true},
+		{" 1 GB ", 1 << 30, true},
+		{"2k", 2 << 10, true},
+		{"3M", 3 << 20, true},
+		{"100B", 100, true},
+		{"0", 0, true},
+		{"", 0, false},
+		{"MB", 0, false},
+		{"-1MB", 0, false},
+		{"1.5MB", 0, false},
+		{"10TB", 0, false},
+		{"9999999999GB", 0, false},
+	}
+	for _, tt := range tests {
+		n, err := ParseSize(tt.in)
+		if (err == nil) != tt.ok || n != tt.want {
+			t.Errorf("ParseSize(%q) = %d, %v, want %d, ok %v", tt.in, n, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestBodyLimits(t *testing.T) {
+	app := New()
+	app.MaxBodyBytes = 8
+	echo := func(w http.ResponseWriter, r *http.Request) {
+		body, err := readBody(r)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+		w.Write(body)
+	}
+	app.HandleFunc("api", "path", "/default", echo)
+	app.HandleFunc("api", "path", "/small", echo, MaxBodyBytes(4))
+	app.HandleFunc("api", "path", "/unlimited", echo, MaxBodyBytes(-1))
+	app.HandleFunc("api", "path", "/json", echo, ContentTypes("application/json", "text/*"))
+	app.LimitBody("local", 16)
+	app.HandleFunc("local", "path", "/entry", echo)
+
+	tests := []struct {
+		method, entry, path string
+		contentType, body   string
+		// 为true时不设置ContentLength,由读取时的限制检查
+		chunked bool
+		status  int
+		header  string
+	}{
+		{"POST", "", "/default", "", "12345678", false, 200, ""},
+		{"POST", "", "/default", "", "123456789", false, 413, ""},
+		{"POST", "", "/default", "", "123456789", true, 413, ""},
+		{"POST", "", "/small", "", "12345", false, 413, ""},
+		{"POST", "", "/small", "", "1234", true, 200, ""},
+		{"POST", "", "/unlimited", "", strings.Repeat("x", 100), false, 200, ""},
+		{"POST", "local", "/entry", "", strings.Repeat("x", 16), false, 200, ""},
+		{"POST", "local", "/entry", "", strings.Repeat("x", 17), true, 413, ""},
+		{"POST", "", "/json", "application/json; charset=utf-8", "{}", false, 200, ""},
+		{"POST", "", "/json", "text/plain", "hi", false, 200, ""},
+		{"POST", "", "/json", "application/xml", "<a/>", false, 415, "Accept-Post"},
+		{"PATCH", "", "/json", "application/xml", "<a/>", false, 415, "Accept-Patch"},
+		{"PUT", "", "/json", "", "x", false, 415, ""},
+		{"GET", "", "/json", "", "", false, 200, ""},
+	}
+	for _, tt := range tests {
+		var body io.Reader = strings.NewReader(tt.body)
+		if tt.chunked {
+			body = io.MultiReader(body)
+		}
+		r := httptest.NewRequest(tt.method, tt.path, body)
+		if tt.entry != "" {
+			r.Header.Set("Faas-Gateway-Name", tt.entry)
+		}
+		if tt.contentType != "" {
+			r.Header.Set("Content-Type", tt.contentType)
+		}
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, r)
+		if w.Code != tt.status {
+			t.Errorf("%s %s %q: status = %d, want %d", tt.method, tt.path, tt.body, w.Code, tt.status)
+			continue
+		}
+		if tt.status == http.StatusOK && w.Body.String() != tt.body {
+			t.Errorf("%s %s: body = %q, want %q", tt.method, tt.path, w.Body.String(), tt.body)
+		}
+		if tt.status == http.StatusUnsupportedMediaType {
+			if w.Header().Get("Accept") != "" {
+				t.Errorf("%s %s: 415 sets the Accept request header", tt.method, tt.path)
+			}
+			if tt.header != "" && w.Header().Get(tt.header) != "application/json, text/*" {
+				t.Errorf("%s %s: %s = %q", tt.method, tt.path, tt.header, w.Header().Get(tt.header))
+			}
+			if !strings.Contains(w.Body.String(), "application/json, text/*") {
+				t.Errorf("%s %s: body %q does not list the accepted types", tt.method, tt.path, w.Body.String())
+			}
+		}
+	}
+}
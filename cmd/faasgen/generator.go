@@ -3,11 +3,13 @@ package main
 import (
 	"errors"
 	"fmt"
+	"go/token"
 	"log"
 	"os"
 	"sort"
 	"strconv"
 	"text/template"
+	"time"
 )
 
 const generatedFileTemplate = `// Code generated by faasgen. DO NOT EDIT.
@@ -15,57 +17,150 @@ const generatedFileTemplate = `// Code generated by faasgen. DO NOT EDIT.
 package main
 
 import (
+	{{- if .OpenAPI }}
+	_ "embed"
+	{{- end }}
 	"github.com/faasteam/faas"
 	{{- range .Imports }}
 	{{ . }}
 	{{- end }}
 )
-
+{{ if .App }}
+var {{ .App }} = faas.New()
+{{ end }}
+{{- if .OpenAPI }}
+//go:embed {{ .OpenAPI }}
+var openAPIDocument []byte
+{{ end }}
+{{- define "routeopts" }}
+{{- if .HTTPAnnotation.Methods }}, faas.Methods({{ range $i, $m := .HTTPAnnotation.Methods }}{{ if $i }}, {{ end }}"{{ $m }}"{{ end }}){{ end }}
+{{- if .HTTPAnnotation.Timeout }}, faas.Timeout({{ duration .HTTPAnnotation.Timeout }}){{ end }}
+{{- if .HTTPAnnotation.MaxBody }}, faas.MaxBodyBytes({{ .HTTPAnnotation.MaxBody }}){{ end }}
+{{- if .HTTPAnnotation.Consumes }}, faas.ContentTypes({{ range $i, $t := .HTTPAnnotation.Consumes }}{{ if $i }}, {{ end }}"{{ $t }}"{{ end }}){{ end }}
+{{- if .HTTPAnnotation.NoAuth }}, faas.NoAuth(){{ end }}
+{{- range .HTTPAnnotation.AuthFunclets }}
+{{- if .HTTPAnnotation.Principal }}, faas.WithAuthFunc(faas.TypedAuth({{ .Package }}{{ .Name }})){{ else if .HTTPAnnotation.ReturnErr }}, faas.WithAuthFunc({{ .Package }}{{ .Name }}){{ else }}, faas.WithAuth({{ .Package }}{{ .Name }}){{ end }}
+{{- end }}
+{{- end }}
 func init() {
+	{{- if .OpenAPI }}
+	{{ $.Recv }}.SetOpenAPI(openAPIDocument)
+	{{- end }}
+
+	{{- range .AuthFunclets }}
+	{{- if .HTTPAnnotation.Principal }}
+	{{ $.Recv }}.HandleAuthFunc("{{ .HTTPAnnotation.Entry }}",  faas.TypedAuth({{ .Package }}{{ .Name }}))
+	{{- else if .HTTPAnnotation.ReturnErr }}
+	{{ $.Recv }}.HandleAuthFunc("{{ .HTTPAnnotation.Entry }}",  {{ .Package }}{{ .Name }})
+	{{- else }}
+	{{ $.Recv }}.HandleAuth("{{ .HTTPAnnotation.Entry }}",  {{ .Package }}{{ .Name }})
+	{{- end }}
+	{{- end }}
+
 	{{- range .HTTPFunclets }}
-	{{- if eq .HTTPAnnotation.FuncletType "onAuthFunclet" }}
-	faas.HandleAuth("{{ .HTTPAnnotation.Entry }}",  {{ .Package }}{{ .Name }})
+	{{- if and (eq .HTTPAnnotation.FuncletType "onMessageFunclet") .HTTPAnnotation.ReturnErr }}
+	{{ $.Recv }}.HandleFunc("{{ .HTTPAnnotation.Entry }}", "{{ .HTTPAnnotation.Type }}", "{{ .HTTPAnnotation.Path }}", faas.MessageErrorHandler({{ .Package }}{{ .Name }}){{ template "routeopts" . }})
 	{{- else if eq .HTTPAnnotation.FuncletType "onMessageFunclet" }}
-	faas.HandleFunc("{{ .HTTPAnnotation.Entry }}", "{{ .HTTPAnnotation.Type }}", "{{ .HTTPAnnotation.Path }}", faas.MessageHandler({{ .Package }}{{ .Name }}))
+	{{ $.Recv }}.HandleFunc("{{ .HTTPAnnotation.Entry }}", "{{ .HTTPAnnotation.Type }}", "{{ .HTTPAnnotation.Path }}", faas.MessageHandler({{ .Package }}{{ .Name }}){{ template "routeopts" . }})
 	{{- else if eq .HTTPAnnotation.FuncletType "onGattFunclet" }}
-	faas.HandleFunc("{{ .HTTPAnnotation.Entry }}", "{{ .HTTPAnnotation.Type }}", "{{ .HTTPAnnotation.Path }}", faas.GattHandler({{ .Package }}{{ .Name }}, "{{ .HTTPAnnotation.ResPath }}"))
+	{{ $.Recv }}.HandleFunc("{{ .HTTPAnnotation.Entry }}", "{{ .HTTPAnnotation.Type }}", "{{ .HTTPAnnotation.Path }}", faas.GattHandler({{ .Package }}{{ .Name }}, "{{ .HTTPAnnotation.ResPath }}"){{ template "routeopts" . }})
 	{{- else if eq .HTTPAnnotation.FuncletType "onStaticFunclet" }}
-	faas.HandleFunc("{{ .HTTPAnnotation.Entry }}", "{{ .HTTPAnnotation.Type }}", "{{ .HTTPAnnotation.Path }}", faas.StaticHandler({{ .Package }}{{ .Name }}, "{{ .HTTPAnnotation.ResPath }}"))
+	{{ $.Recv }}.HandleFunc("{{ .HTTPAnnotation.Entry }}", "{{ .HTTPAnnotation.Type }}", "{{ .HTTPAnnotation.Path }}", faas.StaticHandler({{ .Package }}{{ .Name }}, "{{ .HTTPAnnotation.ResPath }}"){{ template "routeopts" . }})
+	{{- else if and (eq .HTTPAnnotation.FuncletType "onHandleFunclet") .HTTPAnnotation.JSON }}
+	{{ $.Recv }}.HandleFunc("{{ .HTTPAnnotation.Entry }}", "{{ .HTTPAnnotation.Type }}", "{{ .HTTPAnnotation.Path }}", faas.JSONHandler({{ .Package }}{{ .Name }}){{ template "routeopts" . }})
+	{{- else if and (eq .HTTPAnnotation.FuncletType "onHandleFunclet") .HTTPAnnotation.ReturnErr (eq .HTTPAnnotation.ParamCnt 2) }}
+	{{ $.Recv }}.HandleFunc("{{ .HTTPAnnotation.Entry }}", "{{ .HTTPAnnotation.Type }}", "{{ .HTTPAnnotation.Path }}", faas.ErrorHandler({{ .Package }}{{ .Name }}){{ template "routeopts" . }})
+	{{- else if and (eq .HTTPAnnotation.FuncletType "onHandleFunclet") .HTTPAnnotation.ReturnErr (eq .HTTPAnnotation.ParamCnt 3) }}
+	{{ $.Recv }}.HandleFunc("{{ .HTTPAnnotation.Entry }}", "{{ .HTTPAnnotation.Type }}", "{{ .HTTPAnnotation.Path }}", faas.WithContextErrorHandler({{ .Package }}{{ .Name }}){{ template "routeopts" . }})
 	{{- else if and (eq .HTTPAnnotation.FuncletType "onHandleFunclet") (eq .HTTPAnnotation.ParamCnt 2) }}
-	faas.HandleFunc("{{ .HTTPAnnotation.Entry }}", "{{ .HTTPAnnotation.Type }}", "{{ .HTTPAnnotation.Path }}", {{ .Package }}{{ .Name }})
+	{{ $.Recv }}.HandleFunc("{{ .HTTPAnnotation.Entry }}", "{{ .HTTPAnnotation.Type }}", "{{ .HTTPAnnotation.Path }}", {{ .Package }}{{ .Name }}{{ template "routeopts" . }})
 	{{- else if and (eq .HTTPAnnotation.FuncletType "onHandleFunclet") (eq .HTTPAnnotation.ParamCnt 3) }}
-	faas.HandleFunc("{{ .HTTPAnnotation.Entry }}", "{{ .HTTPAnnotation.Type }}", "{{ .HTTPAnnotation.Path }}", faas.WithContextHandler({{ .Package }}{{ .Name }}))
+	{{ $.Recv }}.HandleFunc("{{ .HTTPAnnotation.Entry }}", "{{ .HTTPAnnotation.Type }}", "{{ .HTTPAnnotation.Path }}", faas.WithContextHandler({{ .Package }}{{ .Name }}){{ template "routeopts" . }})
     {{- end }}
 	{{- end }}
 
+	{{- range .Middlewares }}
+	{{- if eq .MiddlewareAnnotation.Type "prefix" }}
+	{{ $.Recv }}.UsePrefix("{{ .MiddlewareAnnotation.Entry }}", "{{ .MiddlewareAnnotation.Path }}", {{ .Package }}{{ .Name }})
+	{{- else if eq .MiddlewareAnnotation.Type "path" }}
+	{{ $.Recv }}.UsePath("{{ .MiddlewareAnnotation.Entry }}", "{{ .MiddlewareAnnotation.Path }}", {{ .Package }}{{ .Name }})
+	{{- else }}
+	{{ $.Recv }}.Use("{{ .MiddlewareAnnotation.Entry }}", {{ .Package }}{{ .Name }})
+	{{- end }}
+	{{- end }}
+
 	{{- if .GattEntry }}
-	faas.GattEntry("{{ .GattEntry.HTTPAnnotation.Entry }}", "{{ .GattEntry.HTTPAnnotation.Path }}", {{ .GattEntry.Package }}{{ .GattEntry.Name }}, "{{ .GattEntry.HTTPAnnotation.ResPath }}")
+	{{ $.Recv }}.GattEntry("{{ .GattEntry.HTTPAnnotation.Entry }}", "{{ .GattEntry.HTTPAnnotation.Path }}", {{ .GattEntry.Package }}{{ .GattEntry.Name }}, "{{ .GattEntry.HTTPAnnotation.ResPath }}")
 	{{- end }}
 
 	{{- range .GattFunclets }}
-	faas.RegisterGattFnHandler("{{ .HTTPAnnotation.Path }}", {{ .Package }}{{ .Name }})
+	{{ $.Recv }}.RegisterGattFnHandler("{{ .HTTPAnnotation.Path }}", {{ .Package }}{{ .Name }})
+	{{- end }}
+
+	{{- range .StartFunclets }}
+	{{ $.Recv }}.OnStart({{ .Package }}{{ .Name }})
+	{{- end }}
+
+	{{- range .StopFunclets }}
+	{{ $.Recv }}.OnStop({{ .Package }}{{ .Name }})
 	{{- end }}
 
 	{{- range .TimingFunclets }}
-	faas.TimingFunc("{{ .TimingAnnotation.Type }}", "{{ .TimingAnnotation.Interval }}", {{ .Package }}{{ .Name }})
+	{{ $.Recv }}.TimingFunc("{{ .TimingAnnotation.Type }}", "{{ .TimingAnnotation.Interval }}", {{ .Package }}{{ .Name }}{{ if .TimingAnnotation.TZ }}, faas.WithTimeZone("{{ .TimingAnnotation.TZ }}"){{ end }}{{ if .TimingAnnotation.Policy }}, faas.WithConcurrency("{{ .TimingAnnotation.Policy }}"){{ end }})
 	{{- end }}
 }
 
 func main() {
-	faas.Run()
+	{{ .Recv }}.Run()
 }
 `
 
 type TemplateData struct {
+	AuthFunclets   []*Funclet
 	HTTPFunclets   []*Funclet
 	GattEntry      *Funclet
 	GattFunclets   []*Funclet
 	TimingFunclets []*Funclet
+	StartFunclets  []*Funclet
+	StopFunclets   []*Funclet
+	Middlewares    []*Funclet
 	Imports        []string
+	App            string
+	Recv           string
+	OpenAPI        string // 嵌入生成代码并由运行时提供的OpenAPI文档,相对于生成文件所在的目录
 }
 
-func generateCode(funclets []*Funclet, outputPath string) error {
-	data := TemplateData{}
+// generateCode 生成注册代码,app非空时生成一个名为app的faas.App变量并注册到该变量上
+func generateCode(data *TemplateData, outputPath string) error {
+	tmpl, err := template.New("faasgen").Funcs(template.FuncMap{"duration": durationExpr}).Parse(generatedFileTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse template: %w", err)
+	}
+	os.Remove(outputPath)
+	outputFile, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer outputFile.Close()
+
+	err = tmpl.Execute(outputFile, data)
+	if err != nil {
+		return fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	log.Printf("Generated code to %s\n", outputPath)
+	return nil
+}
+
+// buildTemplateData 检查funclet之间的冲突并按注册顺序整理,供生成代码和输出路由表使用
+func buildTemplateData(funclets []*Funclet, app string) (*TemplateData, error) {
+	data := &TemplateData{App: app, Recv: "faas"}
+	if app != "" {
+		if !token.IsIdentifier(app) {
+			return nil, fmt.Errorf("invalid app variable name: %s", app)
+		}
+		data.Recv = app
+	}
 	importMap := make(map[string]int)
 	pathMap := make(map[string]string)
 	prefixMap := make(map[string]string)
@@ -73,23 +168,31 @@ func generateCode(funclets []*Funclet, outputPath string) error {
 
 	for _, f := range funclets {
 		if f.HTTPAnnotation != nil {
-			key := f.HTTPAnnotation.Entry + f.HTTPAnnotation.Path
-			if f.HTTPAnnotation.Type == "path" {
-				if pathMap[key] == "" {
-					pathMap[key] = f.ImportPath + "@" + f.Name
-				} else {
-					return errors.New("path pattern conflict: " + f.HTTPAnnotation.Path + "   " + pathMap[key] + "  <------>  " + f.ImportPath + "@" + f.Name + "")
-				}
-			} else if f.HTTPAnnotation.Type == "prefix" {
-				if prefixMap[key] == "" {
-					prefixMap[key] = f.ImportPath + "@" + f.Name
-				} else {
-					return errors.New("prefix pattern conflict: " + f.HTTPAnnotation.Path + "   " + prefixMap[key] + "  <------>  " + f.ImportPath + "@" + f.Name + "")
+			for _, m := range routeMethods(f.HTTPAnnotation) {
+				key := f.HTTPAnnotation.Entry + f.HTTPAnnotation.Path + " " + m
+				if f.HTTPAnnotation.Type == "path" {
+					if pathMap[key] == "" {
+						pathMap[key] = f.ImportPath + "@" + f.Name
+					} else {
+						return nil, errors.New("path pattern conflict: " + m + " " + f.HTTPAnnotation.Path + "   " + pathMap[key] + "  <------>  " + f.ImportPath + "@" + f.Name + "")
+					}
+				} else if f.HTTPAnnotation.Type == "prefix" {
+					if prefixMap[key] == "" {
+						prefixMap[key] = f.ImportPath + "@" + f.Name
+					} else {
+						return nil, errors.New("prefix pattern conflict: " + m + " " + f.HTTPAnnotation.Path + "   " + prefixMap[key] + "  <------>  " + f.ImportPath + "@" + f.Name + "")
+					}
 				}
 			}
 		}
 	}
 
+	for _, f := range funclets {
+		if f.HTTPAnnotation != nil && f.HTTPAnnotation.Timeout != "" && f.HTTPAnnotation.Timeout != "none" {
+			data.Imports = append(data.Imports, "\"time\"")
+			break
+		}
+	}
 	for _, f := range funclets {
 		if f.ImportPath != "" {
 			index, ok := importMap[f.ImportPath]
@@ -104,51 +207,181 @@ func generateCode(funclets []*Funclet, outputPath string) error {
 		if f.HTTPAnnotation != nil {
 			if f.HTTPAnnotation.FuncletType == "onGattEntry" {
 				if data.GattEntry != nil {
-					return errors.New("GattEntry can only be defined once")
+					return nil, errors.New("GattEntry can only be defined once")
 				}
 				data.GattEntry = f
 			} else if f.HTTPAnnotation.FuncletType == "onGattFunclet" {
 				data.GattFunclets = append(data.GattFunclets, f)
+			} else if f.HTTPAnnotation.FuncletType == "onAuthFunclet" {
+				if f.HTTPAnnotation.AuthName == "" {
+					data.AuthFunclets = append(data.AuthFunclets, f)
+				}
 			} else {
-				key := f.HTTPAnnotation.Entry + f.HTTPAnnotation.Path
-				if f.HTTPAnnotation.Type == "prefix" && pathMap[key] == "" && f.HTTPAnnotation.Path != "/" {
-					f2 := *f
-					f2.HTTPAnnotation = new(HTTPAnnotation)
-					*f2.HTTPAnnotation = *f.HTTPAnnotation
-					f2.HTTPAnnotation.Type = "path"
-					data.HTTPFunclets = append(data.HTTPFunclets, &f2)
+				if f.HTTPAnnotation.Type == "prefix" && f.HTTPAnnotation.Path != "/" {
+					// 前缀模式同时匹配不带/结尾的路径,已有path funclet的方法除外
+					var methods []string
+					for _, m := range routeMethods(f.HTTPAnnotation) {
+						if pathMap[f.HTTPAnnotation.Entry+f.HTTPAnnotation.Path+" "+m] == "" {
+							methods = append(methods, m)
+						}
+					}
+					if len(methods) != 0 {
+						f2 := *f
+						f2.HTTPAnnotation = new(HTTPAnnotation)
+						*f2.HTTPAnnotation = *f.HTTPAnnotation
+						f2.HTTPAnnotation.Type = "path"
+						if len(f.HTTPAnnotation.Methods) != 0 {
+							f2.HTTPAnnotation.Methods = methods
+						}
+						data.HTTPFunclets = append(data.HTTPFunclets, &f2)
+					}
 				}
 				data.HTTPFunclets = append(data.HTTPFunclets, f)
 			}
 		} else if f.TimingAnnotation != nil {
 			data.TimingFunclets = append(data.TimingFunclets, f)
+		} else if f.MiddlewareAnnotation != nil {
+			data.Middlewares = append(data.Middlewares, f)
+		} else if f.LifecycleAnnotation != nil {
+			if f.LifecycleAnnotation.Type == "onStartFunclet" {
+				data.StartFunclets = append(data.StartFunclets, f)
+			} else {
+				data.StopFunclets = append(data.StopFunclets, f)
+			}
 		}
 	}
-	sort.Slice(data.HTTPFunclets, func(i, j int) bool {
+	if err := resolveAuth(funclets, data.AuthFunclets); err != nil {
+		return nil, err
+	}
+	if err := checkPatternConflicts(append(data.HTTPFunclets, data.GattEntry)); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(data.HTTPFunclets, func(i, j int) bool {
 		return data.HTTPFunclets[i].HTTPAnnotation.Path < data.HTTPFunclets[j].HTTPAnnotation.Path
 	})
 	if data.GattEntry == nil && len(data.GattFunclets) != 0 {
-		return fmt.Errorf("not found GattEntry")
+		return nil, fmt.Errorf("not found GattEntry")
 	}
 	sort.Slice(data.GattFunclets, func(i, j int) bool {
 		return data.GattFunclets[i].HTTPAnnotation.Path < data.GattFunclets[j].HTTPAnnotation.Path
 	})
-	tmpl, err := template.New("faasgen").Parse(generatedFileTemplate)
-	if err != nil {
-		return fmt.Errorf("failed to parse template: %w", err)
-	}
-	os.Remove(outputPath)
-	outputFile, err := os.Create(outputPath)
-	if err != nil {
-		return fmt.Errorf("failed to create output file: %w", err)
+	sortByOrder(data.StartFunclets, func(f *Funclet) int { return f.LifecycleAnnotation.Order })
+	sortByOrder(data.StopFunclets, func(f *Funclet) int { return f.LifecycleAnnotation.Order })
+	sortByOrder(data.Middlewares, func(f *Funclet) int { return f.MiddlewareAnnotation.Order })
+	for _, f := range data.Middlewares {
+		if err := checkMiddlewarePath(f, data.HTTPFunclets); err != nil {
+			return nil, err
+		}
 	}
-	defer outputFile.Close()
+	return data, nil
+}
 
-	err = tmpl.Execute(outputFile, data)
-	if err != nil {
-		return fmt.Errorf("failed to execute template: %w", err)
+// sortByOrder 按Order、导入路径、函数名排序,保证生成的注册顺序稳定
+func sortByOrder(funclets []*Funclet, order func(f *Funclet) int) {
+	sort.SliceStable(funclets, func(i, j int) bool {
+		a, b := funclets[i], funclets[j]
+		if order(a) != order(b) {
+			return order(a) < order(b)
+		}
+		if a.ImportPath != b.ImportPath {
+			return a.ImportPath < b.ImportPath
+		}
+		return a.Name < b.Name
+	})
+}
+
+// resolveAuth 检查并排序各入口的鉴权链,同一入口有多个鉴权funclet时必须都指定不同的order,
+// 并将@auth引用的具名鉴权funclet解析到AuthFunclets
+func resolveAuth(funclets, chain []*Funclet) error {
+	byEntry := make(map[string][]*Funclet)
+	for _, f := range chain {
+		byEntry[f.HTTPAnnotation.Entry] = append(byEntry[f.HTTPAnnotation.Entry], f)
 	}
+	for entry, auths := range byEntry {
+		if len(auths) < 2 {
+			continue
+		}
+		orders := make(map[int]*Funclet)
+		for _, f := range auths {
+			if !f.HTTPAnnotation.HasOrder {
+				return errors.New("auth conflict: entry " + entry + " has " + strconv.Itoa(len(auths)) + " auth funclets, " + f.ImportPath + "@" + f.Name + " must specify order(N)")
+			}
+			if g, ok := orders[f.HTTPAnnotation.Order]; ok {
+				return errors.New("auth conflict: entry " + entry + " order(" + strconv.Itoa(f.HTTPAnnotation.Order) + ")   " + g.ImportPath + "@" + g.Name + "  <------>  " + f.ImportPath + "@" + f.Name)
+			}
+			orders[f.HTTPAnnotation.Order] = f
+		}
+	}
+	sort.SliceStable(chain, func(i, j int) bool {
+		a, b := chain[i].HTTPAnnotation, chain[j].HTTPAnnotation
+		if a.Entry != b.Entry {
+			return a.Entry < b.Entry
+		}
+		return a.Order < b.Order
+	})
 
-	log.Printf("Generated code to %s\n", outputPath)
+	named := make(map[string]*Funclet)
+	for _, f := range funclets {
+		if f.HTTPAnnotation != nil && f.HTTPAnnotation.AuthName != "" {
+			key := f.HTTPAnnotation.Entry + " " + f.HTTPAnnotation.AuthName
+			if g, ok := named[key]; ok {
+				return errors.New("auth conflict: entry " + f.HTTPAnnotation.Entry + " name " + f.HTTPAnnotation.AuthName + "   " + g.ImportPath + "@" + g.Name + "  <------>  " + f.ImportPath + "@" + f.Name)
+			}
+			named[key] = f
+		}
+	}
+	for _, f := range funclets {
+		if f.HTTPAnnotation == nil || len(f.HTTPAnnotation.Auths) == 0 {
+			continue
+		}
+		f.HTTPAnnotation.AuthFunclets = nil
+		for _, name := range f.HTTPAnnotation.Auths {
+			auth, ok := named[f.HTTPAnnotation.Entry+" "+name]
+			if !ok {
+				return errors.New(f.ImportPath + "@" + f.Name + ": auth funclet " + name + " not found in entry " + f.HTTPAnnotation.Entry)
+			}
+			f.HTTPAnnotation.AuthFunclets = append(f.HTTPAnnotation.AuthFunclets, auth)
+		}
+	}
 	return nil
 }
+
+// checkMiddlewarePath 检查path作用范围的中间件是否有对应的path路由
+func checkMiddlewarePath(mw *Funclet, httpFunclets []*Funclet) error {
+	annot := mw.MiddlewareAnnotation
+	if annot.Type != "path" {
+		return nil
+	}
+	for _, f := range httpFunclets {
+		if f.HTTPAnnotation.Entry == annot.Entry && f.HTTPAnnotation.Type == "path" && f.HTTPAnnotation.Path == annot.Path {
+			return nil
+		}
+	}
+	return errors.New("middleware " + mw.ImportPath + "@" + mw.Name + ": no path funclet found for " + annot.Entry + " " + annot.Path)
+}
+
+// routeMethods 返回冲突检查使用的方法列表,不限方法时为*
+func routeMethods(annot *HTTPAnnotation) []string {
+	if len(annot.Methods) == 0 {
+		return []string{"*"}
+	}
+	return annot.Methods
+}
+
+// durationExpr 将timeout选项转换为Go表达式,none为-1表示不限时
+func durationExpr(s string) string {
+	if s == "none" {
+		return "-1"
+	}
+	d, _ := time.ParseDuration(s)
+	units := []struct {
+		d    time.Duration
+		name string
+	}{{time.Hour, "time.Hour"}, {time.Minute, "time.Minute"}, {time.Second, "time.Second"}, {time.Millisecond, "time.Millisecond"}}
+	for _, u := range units {
+		if d%u.d == 0 {
+			return strconv.FormatInt(int64(d/u.d), 10) + " * " + u.name
+		}
+	}
+	return "time.Duration(" + strconv.FormatInt(int64(d), 10) + ")"
+}
@@ -2,7 +2,7 @@ package main
 
 import (
 	"flag"
-
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
@@ -10,14 +10,63 @@ import (
 )
 
 func main() {
-	var allFunclets []*Funclet
 	var (
-		src    = flag.String("src", "", "Source file or directory to scan for annotations.")
-		output = flag.String("output", "main.go", "Output file name for generated faas code.")
+		src       = flag.String("src", "", "Source file or directory to scan for annotations.")
+		output    = flag.String("output", "main.go", "Output file name for generated faas code.")
+		app       = flag.String("app", "", "Variable name of a faas.App to register funclets on, empty for the package-level default App.")
+		typecheck = flag.Bool("typecheck", true, "Type-check funclet signatures against their annotations.")
+		openapi   = flag.String("openapi", "", "Output file for an OpenAPI 3 document generated from the annotations, empty to skip. A file in the output package is embedded and served by the runtime.")
 	)
 
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: faasgen [flags]\n       faasgen routes [-src dir] [-format text|json]\n\nFlags:")
+		flag.PrintDefaults()
+	}
+	if len(os.Args) > 1 && os.Args[1] == "routes" {
+		routesCommand(os.Args[2:])
+		return
+	}
 	flag.Parse()
 	log.Println("start generate faas code . . .")
+	allFunclets, modulePath := scanFunclets(*src)
+
+	if len(allFunclets) == 0 {
+		log.Println("No funclets found. Exiting.")
+		return
+	}
+
+	tc := newTypeChecker(*output)
+	if *typecheck {
+		if err := tc.checkFunclets(allFunclets); err != nil {
+			log.Fatalf("Error checking funclets:\n%v", err)
+		}
+	}
+
+	data, err := buildTemplateData(allFunclets, *app)
+	if err != nil {
+		log.Fatalf("Error generating code: %v", err)
+	}
+	if *openapi != "" {
+		if err := generateOpenAPI(tc, data, modulePath, *openapi); err != nil {
+			log.Fatalf("Error generating OpenAPI document: %v", err)
+		}
+		rel, err := filepath.Rel(filepath.Dir(*output), *openapi)
+		if err == nil && !strings.HasPrefix(rel, "..") {
+			data.OpenAPI = filepath.ToSlash(rel)
+		} else {
+			log.Printf("OpenAPI document %s is outside the output package, not embedded", *openapi)
+		}
+	}
+	if err := generateCode(data, *output); err != nil {
+		log.Fatalf("Error generating code: %v", err)
+	}
+	log.Println("Code generation complete.")
+}
+
+// scanFunclets 读取go.mod中的模块路径,解析src文件或目录中的funclet注解,src为空时使用faas.go或server目录,
+// 返回funclet和模块路径
+func scanFunclets(src string) ([]*Funclet, string) {
+	var all []*Funclet
 	goModPath := "go.mod"
 	goModContent, err := os.ReadFile(goModPath)
 	if err != nil {
@@ -28,26 +77,26 @@ func main() {
 		log.Fatalf("Could not find module path in go.mod")
 	}
 
-	if *src == "" {
+	if src == "" {
 		fi, err := os.Stat("faas.go")
 		if err == nil && !fi.IsDir() {
-			*src = "faas.go"
+			src = "faas.go"
 		} else {
-			*src = "server"
+			src = "server"
 		}
 	}
-	fileInfo, err := os.Stat(*src)
+	fileInfo, err := os.Stat(src)
 	if err != nil {
-		log.Fatalf("Could not find file or directory: %s", *src)
+		log.Fatalf("Could not find file or directory: %s", src)
 	}
 	if !fileInfo.IsDir() {
-		funclets, err := parseFile(*src, modulePath)
+		funclets, err := parseFile(src, modulePath)
 		if err != nil {
-			log.Fatalf("Error parsing file %s: %v", *src, err)
+			log.Fatalf("Error parsing file %s: %v", src, err)
 		}
-		allFunclets = append(allFunclets, funclets...)
+		all = append(all, funclets...)
 	} else {
-		srcDir := *src
+		srcDir := src
 		err = filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
 			if err != nil {
 				return err
@@ -60,7 +109,7 @@ func main() {
 				if err != nil {
 					log.Fatalf("Error parsing file %s: %v", path, err)
 				}
-				allFunclets = append(allFunclets, funclets...)
+				all = append(all, funclets...)
 			}
 			return nil
 		})
@@ -68,16 +117,7 @@ func main() {
 			log.Fatalf("Error walking src directory: %v", err)
 		}
 	}
-
-	if len(allFunclets) == 0 {
-		log.Println("No funclets found. Exiting.")
-		return
-	}
-
-	if err := generateCode(allFunclets, *output); err != nil {
-		log.Fatalf("Error generating code: %v", err)
-	}
-	log.Println("Code generation complete.")
+	return all, modulePath
 }
 
 func findModulePath(goModContent string) string {
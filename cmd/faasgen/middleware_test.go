@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func middlewareFunclet(name, importPath, entry, typ, path string, order int) *Funclet {
+	return &Funclet{Name: name, ImportPath: importPath, MiddlewareAnnotation: &MiddlewareAnnotation{
+		Entry: entry,
+		Type:  typ,
+		Path:  path,
+		Order: order,
+	}}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	data, err := buildTemplateData([]*Funclet{
+		handleFunclet("User", "api", "path", "/users/{id}"),
+		middlewareFunclet("Recover", "example/b", "api", "", "", -1),
+		middlewareFunclet("UserLog", "example/a", "api", "path", "/users/{id}", 0),
+		middlewareFunclet("Trace", "example/b", "api", "", "", 0),
+		middlewareFunclet("CORS", "example/a", "api", "prefix", "/users", 0),
+		middlewareFunclet("Audit", "example/a", "api", "", "", 0),
+		middlewareFunclet("Limit", "example/a", "api", "", "", 10),
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 按order从小到大,order相同时按导入路径和函数名,小的在外层
+	var names []string
+	for _, f := range data.Middlewares {
+		names = append(names, f.Name)
+	}
+	if got, want := strings.Join(names, " "), "Recover Audit CORS UserLog Trace Limit"; got != want {
+		t.Errorf("middleware order = %s, want %s", got, want)
+	}
+
+	out := filepath.Join(t.TempDir(), "main.go")
+	if err := generateCode(data, out); err != nil {
+		t.Fatal(err)
+	}
+	code, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var calls []string
+	for _, line := range strings.Split(string(code), "\n") {
+		if line = strings.TrimSpace(line); strings.HasPrefix(line, "faas.Use") {
+			calls = append(calls, line)
+		}
+	}
+	want := []string{
+		`faas.Use("api", a0.Recover)`,
+		`faas.Use("api", a1.Audit)`,
+		`faas.UsePrefix("api", "/users", a1.CORS)`,
+		`faas.UsePath("api", "/users/{id}", a1.UserLog)`,
+		`faas.Use("api", a0.Trace)`,
+		`faas.Use("api", a1.Limit)`,
+	}
+	if strings.Join(calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("generated middleware registrations:\n%s\nwant:\n%s", strings.Join(calls, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCheckMiddlewarePath(t *testing.T) {
+	tests := []struct {
+		name string
+		mw   *Funclet
+		err  string
+	}{
+		{"path funclet", middlewareFunclet("Log", "example", "api", "path", "/users/{id}", 0), ""},
+		{"prefix funclet also serves the path", middlewareFunclet("Log", "example", "api", "path", "/static", 0), ""},
+		{"prefix scope", middlewareFunclet("Log", "example", "api", "prefix", "/nothing", 0), ""},
+		{"missing path", middlewareFunclet("Log", "example", "api", "path", "/users", 0), "middleware example@Log: no path funclet found for api /users"},
+		{"other entry", middlewareFunclet("Log", "example", "local", "path", "/users/{id}", 0), "middleware example@Log: no path funclet found for local /users/{id}"},
+	}
+	for _, tt := range tests {
+		_, err := buildTemplateData([]*Funclet{
+			handleFunclet("User", "api", "path", "/users/{id}"),
+			handleFunclet("Static", "api", "prefix", "/static"),
+			tt.mw,
+		}, "")
+		if (err == nil) != (tt.err == "") || (err != nil && err.Error() != tt.err) {
+			t.Errorf("%s: buildTemplateData error = %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
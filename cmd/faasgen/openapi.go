@@ -0,0 +1,491 @@
+package main
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"log"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// schema OpenAPI 3的Schema Object,只包含faasgen用到的字段
+type schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Items                *schema            `json:"items,omitempty"`
+	Properties           map[string]*schema `json:"properties,omitempty"`
+	AdditionalProperties *schema            `json:"additionalProperties,omitempty"`
+	Minimum              *int               `json:"minimum,omitempty"`
+}
+
+type parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *schema `json:"schema"`
+}
+
+type mediaType struct {
+	Schema *schema `json:"schema,omitempty"`
+}
+
+type requestBody struct {
+	Content map[string]mediaType `json:"content"`
+}
+
+type response struct {
+	Description string               `json:"description"`
+	Content     map[string]mediaType `json:"content,omitempty"`
+}
+
+type operation struct {
+	Tags        []string            `json:"tags,omitempty"`
+	Summary     string              `json:"summary,omitempty"`
+	Description string              `json:"description,omitempty"`
+	OperationID string              `json:"operationId"`
+	Parameters  []parameter         `json:"parameters,omitempty"`
+	RequestBody *requestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]response `json:"responses"`
+	//前缀模式的路由,匹配路径下的所有子路径
+	Prefix bool `json:"x-faas-prefix,omitempty"`
+}
+
+type openAPIDocument struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       map[string]string                `json:"info"`
+	Paths      map[string]map[string]*operation `json:"paths"`
+	Components struct {
+		Schemas map[string]*schema `json:"schemas,omitempty"`
+	} `json:"components"`
+}
+
+var wildcardRegex = regexp.MustCompile(`\{(\w+)(\.\.\.)?\}`)
+
+// generateOpenAPI 按onHandleFunclet注解生成OpenAPI 3文档写入outputPath,
+// JSON funclet的请求和响应类型通过类型检查转换为schema
+func generateOpenAPI(tc *typeChecker, data *TemplateData, title, outputPath string) error {
+	doc := &openAPIDocument{
+		OpenAPI: "3.0.3",
+		Info:    map[string]string{"title": title, "version": "1.0.0"},
+		Paths:   make(map[string]map[string]*operation),
+	}
+	sg := &schemaGen{
+		tc:      tc,
+		schemas: make(map[string]*schema),
+		names:   make(map[*types.TypeName]string),
+		docs:    make(map[string]string),
+		parsed:  make(map[string]bool),
+	}
+	entryAuths := make(map[string]int)
+	for _, f := range data.AuthFunclets {
+		entryAuths[f.HTTPAnnotation.Entry]++
+	}
+	// 前缀模式会展开一个同路径的path路由,不单独输出
+	prefixes := make(map[string]bool)
+	for _, f := range data.HTTPFunclets {
+		if f.HTTPAnnotation.Type == "prefix" {
+			prefixes[f.ImportPath+"."+f.Name+" "+f.HTTPAnnotation.Entry+f.HTTPAnnotation.Path] = true
+		}
+	}
+	operationIDs := make(map[string]bool)
+	for _, f := range data.HTTPFunclets {
+		annot := f.HTTPAnnotation
+		if annot.FuncletType != "onHandleFunclet" {
+			continue
+		}
+		if annot.Type == "path" && prefixes[f.ImportPath+"."+f.Name+" "+annot.Entry+annot.Path] {
+			continue
+		}
+		// 前缀路由与运行时的路由模式一样以/结尾,与同路径的path路由区分
+		path := wildcardRegex.ReplaceAllString(routePath(annot.Type, annot.Path), "{$1}")
+		op := &operation{
+			Tags:        []string{annot.Entry},
+			OperationID: f.Name,
+			Responses:   make(map[string]response),
+			Prefix:      annot.Type == "prefix",
+		}
+		if operationIDs[op.OperationID] {
+			// 不同包的同名函数加包名区分
+			op.OperationID = "main_" + f.Name
+			if f.ImportPath != "" {
+				op.OperationID = f.ImportPath[strings.LastIndex(f.ImportPath, "/")+1:] + "_" + f.Name
+			}
+		}
+		operationIDs[op.OperationID] = true
+		op.Summary, op.Description = splitDoc(trimName(f.Doc, f.Name))
+
+		var req, resp types.Type
+		if annot.JSON {
+			pkg, err := tc.load(f.Dir, f.ImportPath)
+			if err != nil {
+				return err
+			}
+			sig := pkg.Scope().Lookup(f.Name).Type().(*types.Signature)
+			req = sig.Params().At(1).Type().(*types.Pointer).Elem()
+			resp = sig.Results().At(0).Type().(*types.Pointer).Elem()
+		}
+		params := map[string]*types.Var{}
+		if req != nil {
+			op.Parameters = sg.params(req, params)
+		}
+		for _, m := range wildcardRegex.FindAllStringSubmatch(annot.Path, -1) {
+			p := parameter{Name: m[1], In: "path", Required: true, Schema: &schema{Type: "string"}}
+			if v, ok := params[m[1]]; ok {
+				p.Schema, p.Description = sg.schema(v.Type()), sg.doc(v.Pos())
+			}
+			op.Parameters = append(op.Parameters, p)
+		}
+
+		methods := annot.Methods
+		if len(methods) == 0 {
+			// 不限方法的路由按GET列出,JSON funclet读取请求体,按POST列出
+			methods = []string{"GET"}
+			if annot.JSON {
+				methods = []string{"POST"}
+			}
+		}
+		if annot.JSON {
+			if body := sg.bodySchema(req); body != nil && hasRequestBody(methods) {
+				op.RequestBody = &requestBody{Content: map[string]mediaType{"application/json": {Schema: body}}}
+			}
+			op.Responses["200"] = response{Description: "OK", Content: map[string]mediaType{"application/json": {Schema: sg.schema(resp)}}}
+			op.Responses["204"] = response{Description: "No Content"}
+			op.Responses["default"] = response{Description: "Error", Content: map[string]mediaType{"application/json": {Schema: sg.errorSchema()}}}
+		} else {
+			if len(annot.Consumes) != 0 && hasRequestBody(methods) {
+				op.RequestBody = &requestBody{Content: make(map[string]mediaType)}
+				for _, t := range annot.Consumes {
+					op.RequestBody.Content[t] = mediaType{}
+				}
+			}
+			op.Responses["default"] = response{Description: "Response"}
+		}
+		if !annot.NoAuth && (len(annot.Auths) != 0 || entryAuths[annot.Entry] != 0) {
+			op.Responses["401"] = response{Description: "Unauthorized"}
+		}
+
+		item := doc.Paths[path]
+		if item == nil {
+			item = make(map[string]*operation)
+			doc.Paths[path] = item
+		}
+		for _, m := range methods {
+			m = strings.ToLower(m)
+			if _, ok := item[m]; ok {
+				log.Printf("openapi: %s %s is defined more than once, keeping the first one", strings.ToUpper(m), path)
+				continue
+			}
+			item[m] = op
+		}
+	}
+	doc.Components.Schemas = sg.schemas
+
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(outputPath, append(out, '\n'), 0644); err != nil {
+		return err
+	}
+	log.Printf("Generated OpenAPI document to %s\n", outputPath)
+	return nil
+}
+
+// hasRequestBody 判断方法中是否有带请求体的方法
+func hasRequestBody(methods []string) bool {
+	for _, m := range methods {
+		if m == "POST" || m == "PUT" || m == "PATCH" || m == "DELETE" {
+			return true
+		}
+	}
+	return false
+}
+
+// splitDoc 将注释拆分为第一行的摘要和其余的描述
+func splitDoc(doc string) (string, string) {
+	summary, description, _ := strings.Cut(strings.TrimSpace(doc), "\n")
+	return strings.TrimSpace(summary), strings.TrimSpace(description)
+}
+
+// trimName 去掉注释开头的名称,如"Item 条目"中的Item
+func trimName(doc, name string) string {
+	if rest, ok := strings.CutPrefix(doc, name+" "); ok {
+		return strings.TrimSpace(rest)
+	}
+	return doc
+}
+
+// schemaGen 将Go类型转换为schema,具名的结构体类型放入components
+type schemaGen struct {
+	tc      *typeChecker
+	schemas map[string]*schema
+	names   map[*types.TypeName]string
+	//文件:行:列对应的注释,按需解析源文件
+	docs   map[string]string
+	parsed map[string]bool
+}
+
+// schema 返回类型的schema,具名结构体返回$ref
+func (sg *schemaGen) schema(t types.Type) *schema {
+	switch t := types.Unalias(t).(type) {
+	case *types.Named:
+		obj := t.Obj()
+		if obj.Pkg() != nil && obj.Pkg().Path() == "time" && obj.Name() == "Time" {
+			return &schema{Type: "string", Format: "date-time"}
+		}
+		if obj.Pkg() != nil && (obj.Pkg().Path() == "encoding/json" && obj.Name() == "RawMessage" ||
+			obj.Pkg().Path() == "encoding/json/jsontext" && obj.Name() == "Value") {
+			return &schema{}
+		}
+		st, ok := t.Underlying().(*types.Struct)
+		if !ok {
+			s := sg.schema(t.Underlying())
+			if s.Description == "" {
+				s.Description = trimName(sg.doc(obj.Pos()), obj.Name())
+			}
+			return s
+		}
+		name, ok := sg.names[obj]
+		if !ok {
+			name = sg.componentName(t)
+			sg.names[obj] = name
+			s := &schema{}
+			sg.schemas[name] = s
+			*s = *sg.structSchema(st, false)
+			s.Description = trimName(sg.doc(obj.Pos()), obj.Name())
+		}
+		return &schema{Ref: "#/components/schemas/" + name}
+	case *types.Pointer:
+		return sg.schema(t.Elem())
+	case *types.Basic:
+		return basicSchema(t)
+	case *types.Slice:
+		if b, ok := t.Elem().Underlying().(*types.Basic); ok && b.Kind() == types.Byte {
+			return &schema{Type: "string", Format: "byte"}
+		}
+		return &schema{Type: "array", Items: sg.schema(t.Elem())}
+	case *types.Array:
+		return &schema{Type: "array", Items: sg.schema(t.Elem())}
+	case *types.Map:
+		return &schema{Type: "object", AdditionalProperties: sg.schema(t.Elem())}
+	case *types.Struct:
+		return sg.structSchema(t, false)
+	}
+	return &schema{}
+}
+
+// componentName 返回components中的名称,不同包的同名类型加包名区分
+func (sg *schemaGen) componentName(t *types.Named) string {
+	name := t.Obj().Name()
+	if t.TypeArgs().Len() != 0 {
+		for i := 0; i < t.TypeArgs().Len(); i++ {
+			name += "_" + nonIdentRegex.ReplaceAllString(types.TypeString(t.TypeArgs().At(i), func(p *types.Package) string { return "" }), "")
+		}
+	}
+	if _, ok := sg.schemas[name]; ok && t.Obj().Pkg() != nil {
+		name = t.Obj().Pkg().Name() + "." + name
+	}
+	return name
+}
+
+var nonIdentRegex = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+func basicSchema(t *types.Basic) *schema {
+	zero := 0
+	switch t.Kind() {
+	case types.Bool, types.UntypedBool:
+		return &schema{Type: "boolean"}
+	case types.Int, types.Int64, types.UntypedInt:
+		return &schema{Type: "integer", Format: "int64"}
+	case types.Int8, types.Int16, types.Int32:
+		return &schema{Type: "integer", Format: "int32"}
+	case types.Uint, types.Uint64, types.Uintptr:
+		return &schema{Type: "integer", Format: "int64", Minimum: &zero}
+	case types.Uint8, types.Uint16, types.Uint32:
+		return &schema{Type: "integer", Format: "int32", Minimum: &zero}
+	case types.Float32:
+		return &schema{Type: "number", Format: "float"}
+	case types.Float64, types.UntypedFloat:
+		return &schema{Type: "number", Format: "double"}
+	case types.String, types.UntypedString:
+		return &schema{Type: "string"}
+	}
+	return &schema{}
+}
+
+// jsonField 按encoding/json的规则返回字段的名称,skip为true时不编码
+func jsonField(v *types.Var, tag string) (name string, asString, skip bool) {
+	if !v.Exported() && !v.Embedded() {
+		return "", false, true
+	}
+	name, opts, _ := strings.Cut(reflect.StructTag(tag).Get("json"), ",")
+	if name == "-" && opts == "" {
+		return "", false, true
+	}
+	if name == "" {
+		name = v.Name()
+	}
+	return name, strings.Contains(","+opts+",", ",string,"), false
+}
+
+// structSchema 返回结构体的schema,嵌入的结构体字段展开,skipParams为true时跳过带path和query标签的字段
+func (sg *schemaGen) structSchema(st *types.Struct, skipParams bool) *schema {
+	s := &schema{Type: "object", Properties: make(map[string]*schema)}
+	for i := 0; i < st.NumFields(); i++ {
+		v, tag := st.Field(i), st.Tag(i)
+		tags := reflect.StructTag(tag)
+		if skipParams && (tags.Get("path") != "" || tags.Get("query") != "") {
+			continue
+		}
+		name, asString, skip := jsonField(v, tag)
+		if skip {
+			continue
+		}
+		if v.Embedded() && tags.Get("json") == "" {
+			t := v.Type()
+			if p, ok := t.Underlying().(*types.Pointer); ok {
+				t = p.Elem()
+			}
+			if est, ok := t.Underlying().(*types.Struct); ok {
+				for k, p := range sg.structSchema(est, skipParams).Properties {
+					if _, ok := s.Properties[k]; !ok {
+						s.Properties[k] = p
+					}
+				}
+				continue
+			}
+			if !v.Exported() {
+				continue
+			}
+		}
+		var fs *schema
+		if asString {
+			fs = &schema{Type: "string"}
+		} else {
+			fs = sg.schema(v.Type())
+		}
+		// $ref的同级字段会被忽略,字段说明只加在非引用的schema上
+		if fs.Ref == "" && fs.Description == "" {
+			fs.Description = sg.doc(v.Pos())
+		}
+		s.Properties[name] = fs
+	}
+	return s
+}
+
+// bodySchema 返回JSON funclet请求体的schema,请求类型有path或query字段时去掉这些字段,没有字段时返回nil
+func (sg *schemaGen) bodySchema(t types.Type) *schema {
+	st, ok := t.Underlying().(*types.Struct)
+	if !ok {
+		return sg.schema(t)
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		tags := reflect.StructTag(st.Tag(i))
+		if tags.Get("path") != "" || tags.Get("query") != "" {
+			s := sg.structSchema(st, true)
+			if len(s.Properties) == 0 {
+				return nil
+			}
+			if named, ok := types.Unalias(t).(*types.Named); ok {
+				s.Description = trimName(sg.doc(named.Obj().Pos()), named.Obj().Name())
+			}
+			return s
+		}
+	}
+	if st.NumFields() == 0 {
+		return nil
+	}
+	return sg.schema(t)
+}
+
+// params 返回请求类型中带query标签的查询参数,并将带path标签的字段按名称记录到path中
+func (sg *schemaGen) params(t types.Type, path map[string]*types.Var) []parameter {
+	st, ok := t.Underlying().(*types.Struct)
+	if !ok {
+		return nil
+	}
+	var list []parameter
+	for i := 0; i < st.NumFields(); i++ {
+		v, tags := st.Field(i), reflect.StructTag(st.Tag(i))
+		if name := tags.Get("path"); name != "" {
+			path[name] = v
+		}
+		if name := tags.Get("query"); name != "" {
+			list = append(list, parameter{Name: name, In: "query", Description: sg.doc(v.Pos()), Schema: sg.schema(v.Type())})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// errorSchema 返回JSON funclet出错时{"error": "..."}的schema
+func (sg *schemaGen) errorSchema() *schema {
+	if _, ok := sg.schemas["Error"]; !ok {
+		sg.schemas["Error"] = &schema{Type: "object", Properties: map[string]*schema{"error": {Type: "string"}}}
+	}
+	return &schema{Ref: "#/components/schemas/Error"}
+}
+
+// doc 返回pos处类型或字段的注释,类型检查时没有保留注释,按需重新解析源文件
+func (sg *schemaGen) doc(pos token.Pos) string {
+	if !pos.IsValid() {
+		return ""
+	}
+	p := sg.tc.fset.Position(pos)
+	if !sg.parsed[p.Filename] {
+		sg.parsed[p.Filename] = true
+		sg.indexDocs(p.Filename)
+	}
+	return sg.docs[p.String()]
+}
+
+// indexDocs 解析文件,按名称的位置记录类型和结构体字段的注释
+func (sg *schemaGen) indexDocs(filename string) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, nil, parser.ParseComments)
+	if err != nil {
+		return
+	}
+	text := func(groups ...*ast.CommentGroup) string {
+		for _, g := range groups {
+			if t := strings.TrimSpace(g.Text()); t != "" {
+				return t
+			}
+		}
+		return ""
+	}
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.GenDecl:
+			if n.Tok == token.TYPE && len(n.Specs) == 1 && n.Doc != nil {
+				spec := n.Specs[0].(*ast.TypeSpec)
+				if spec.Doc == nil {
+					spec.Doc = n.Doc
+				}
+			}
+		case *ast.TypeSpec:
+			if doc := text(n.Doc, n.Comment); doc != "" {
+				sg.docs[fset.Position(n.Name.Pos()).String()] = doc
+			}
+		case *ast.Field:
+			if doc := text(n.Doc, n.Comment); doc != "" {
+				for _, name := range n.Names {
+					sg.docs[fset.Position(name.Pos()).String()] = doc
+				}
+				if len(n.Names) == 0 {
+					sg.docs[fset.Position(n.Type.Pos()).String()] = doc
+				}
+			}
+		}
+		return true
+	})
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files in testdata")
+
+// TestGenerateOpenAPI 与testdata/openapi/openapi.json比较,修改生成逻辑后用-update更新
+func TestGenerateOpenAPI(t *testing.T) {
+	funclets := parseFixture(t, filepath.Join("testdata", "openapi", "server", "funclets.go"))
+	if err := fixtureChecker.checkFunclets(funclets); err != nil {
+		t.Fatal(err)
+	}
+	data, err := buildTemplateData(funclets, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(t.TempDir(), "openapi.json")
+	if err := generateOpenAPI(fixtureChecker, data, "example", out); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden := filepath.Join("testdata", "openapi", "openapi.json")
+	if *update {
+		if err := os.WriteFile(golden, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("generated OpenAPI document differs from %s, run go test -update if the change is intended:\n%s", golden, got)
+	}
+}
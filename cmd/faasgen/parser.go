@@ -5,9 +5,14 @@ import (
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"mime"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/faasteam/faas"
 )
 
 type FuncletType int
@@ -24,26 +29,64 @@ type HTTPAnnotation struct {
 	Path        string
 	ResPath     string
 	ParamCnt    int
+	ReturnErr   bool     // 函数返回error,仅onHandleFunclet、onMessageFunclet和onAuthFunclet支持
+	Principal   bool     // onAuthFunclet返回(T, error),T为调用者身份的类型
+	JSON        bool     // func(*faas.Context, *Req) (*Resp, error)形式的JSON funclet
+	Methods     []string // 允许的HTTP方法,如GET|POST,为空时不限制
+
+	AuthName     string     // 具名的onAuthFunclet不在入口鉴权链中,只用于@auth引用
+	Order        int        // onAuthFunclet在入口鉴权链中的顺序
+	HasOrder     bool       // 是否显式指定了order
+	NoAuth       bool       // @auth none,跳过入口的鉴权
+	Auths        []string   // @auth a,b,用具名鉴权链代替入口的鉴权链
+	AuthFunclets []*Funclet // Auths对应的funclet,生成代码时解析
+
+	Timeout  string   // timeout(5s),处理时长限制,none为不限时,为空时使用服务的默认值
+	MaxBody  int64    // maxbody(1MB),请求体大小限制,-1为不限制,0时使用入口或服务的配置
+	Consumes []string // consumes(application/json|text/*),接受的请求体类型
 }
 
 type TimingAnnotation struct {
-	Type     string // "repeat", "everyday", "once"
-	Interval string // e.g., "5s", "13h"
+	Type     string // "repeat", "everyday", "once", "cron"
+	Interval string // e.g., "5s", "13h", "13:30", "*/15 9-18 * * 1-5"
+	TZ       string // e.g., "Asia/Shanghai", only for everyday and cron
+	Policy   string // "skip", "queue" or "parallel", empty for the runtime default
+}
+
+type LifecycleAnnotation struct {
+	Type  string // "onStartFunclet" or "onStopFunclet"
+	Order int    // 启动钩子按Order从小到大执行,停止钩子按相反顺序执行
+}
+
+type MiddlewareAnnotation struct {
+	Entry string
+	Type  string // ""为整个入口,"prefix"或"path"
+	Path  string
+	Order int // 同一作用范围内按Order从小到大,小的在外层
 }
 
 type Funclet struct {
-	Name             string
-	ImportPath       string
-	Package          string
-	HTTPAnnotation   *HTTPAnnotation
-	TimingAnnotation *TimingAnnotation
+	Name                 string
+	Doc                  string // 去掉注解行的函数注释
+	Pos                  token.Position
+	Dir                  string
+	ImportPath           string
+	Package              string
+	HTTPAnnotation       *HTTPAnnotation
+	TimingAnnotation     *TimingAnnotation
+	LifecycleAnnotation  *LifecycleAnnotation
+	MiddlewareAnnotation *MiddlewareAnnotation
 }
 type MatchAnnotation func(fn *ast.FuncDecl, text string) (*Funclet, error)
 
 var (
-	httpRegex   = regexp.MustCompile(`^//\s*@(onHandleFunclet|onMessageFunclet|onAuthFunclet|onGattEntry|onGattFunclet|onStaticFunclet)\s+(\w+)\s*\((.*?)\)`)
-	timingRegex = regexp.MustCompile(`^//\s*@onTimingFunclet\s+time\s*\(\s*(repeat|everyday|once)\s*(?:,\s*([^)]+)\s*)?\)`)
-	matchSlice  = []MatchAnnotation{matchHTTPAnnotation, matchTimingAnnotation}
+	httpRegex       = regexp.MustCompile(`^//\s*@(onHandleFunclet|onMessageFunclet|onAuthFunclet|onGattEntry|onGattFunclet|onStaticFunclet)\s+(\w+)\s*\((.*?)\)`)
+	timingRegex     = regexp.MustCompile(`^//\s*@onTimingFunclet\s+time\s*\(\s*(repeat|everyday|once|cron)\s*(?:,\s*([^)]+)\s*)?\)`)
+	lifecycleRegex  = regexp.MustCompile(`^//\s*@(onStartFunclet|onStopFunclet)(?:\s+order\s*\(\s*(-?\d+)\s*\))?\s*$`)
+	optionRegex     = regexp.MustCompile(`^\s*(\w+)\s*\(\s*([^)]*?)\s*\)`)
+	authRegex       = regexp.MustCompile(`^//\s*@auth\s+(.+)$`)
+	middlewareRegex = regexp.MustCompile(`^//\s*@onMiddleware\s+(\w+)\s*\((.*?)\)(?:\s+order\s*\(\s*(-?\d+)\s*\))?\s*$`)
+	matchSlice      = []MatchAnnotation{matchHTTPAnnotation, matchTimingAnnotation, matchLifecycleAnnotation, matchMiddlewareAnnotation}
 )
 
 func parseFile(filePath, modulePath string) ([]*Funclet, error) {
@@ -57,30 +100,121 @@ func parseFile(filePath, modulePath string) ([]*Funclet, error) {
 	for _, decl := range node.Decls {
 		if fn, isFn := decl.(*ast.FuncDecl); isFn {
 			if fn.Doc != nil {
+				first := len(funclets)
+				var auths []string
 				for _, comment := range fn.Doc.List {
+					if m := authRegex.FindStringSubmatch(strings.TrimSpace(comment.Text)); m != nil {
+						auths = parseParam(m[1])
+					}
 					text := strings.TrimSpace(comment.Text)
 					for _, mf := range matchSlice {
 						f, err := mf(fn, text)
+						if err == nil && f != nil && fn.Recv != nil {
+							err = errors.New("funclet must be a function, not a method")
+						}
 						if err != nil {
-							return nil, errors.New("path: " + filePath + ",func: " + fn.Name.Name + ",err:" + err.Error())
+							return nil, errors.New(fset.Position(fn.Pos()).String() + ": func " + fn.Name.Name + ": " + err.Error())
 						}
 						if f != nil {
 							f.Name = fn.Name.Name
-							dir := filepath.Dir(filePath)
-							if dir != "" && dir != "." {
-								f.ImportPath = filepath.Join(modulePath, dir)
+							f.Doc = funcletDoc(fn.Doc)
+							f.Pos = fset.Position(fn.Pos())
+							f.Dir = filepath.Dir(filePath)
+							if f.Dir != "" && f.Dir != "." {
+								f.ImportPath = filepath.Join(modulePath, f.Dir)
 							}
 							funclets = append(funclets, f)
 						}
 					}
 
 				}
+				if auths != nil {
+					if err := applyAuth(funclets[first:], auths); err != nil {
+						return nil, errors.New(fset.Position(fn.Pos()).String() + ": func " + fn.Name.Name + ": " + err.Error())
+					}
+				}
 			}
 		}
 	}
 	return funclets, nil
 }
 
+// funcletDoc 返回函数注释中注解行以外的内容
+func funcletDoc(doc *ast.CommentGroup) string {
+	var lines []string
+	for _, line := range strings.Split(doc.Text(), "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "@") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+var httpMethods = map[string]bool{
+	"GET": true, "HEAD": true, "POST": true, "PUT": true, "PATCH": true,
+	"DELETE": true, "CONNECT": true, "OPTIONS": true, "TRACE": true,
+}
+
+// parseMethods 解析GET|POST形式的HTTP方法列表
+func parseMethods(s string) ([]string, error) {
+	var methods []string
+	seen := make(map[string]bool)
+	for _, m := range strings.Split(s, "|") {
+		m = strings.ToUpper(strings.TrimSpace(m))
+		if !httpMethods[m] {
+			return nil, errors.New("Error method " + m + ",only support GET/HEAD/POST/PUT/PATCH/DELETE/CONNECT/OPTIONS/TRACE")
+		}
+		if seen[m] {
+			return nil, errors.New("duplicate method " + m)
+		}
+		seen[m] = true
+		methods = append(methods, m)
+	}
+	return methods, nil
+}
+
+// applyAuth 将@auth注解应用到同一函数的onHandleFunclet和onStaticFunclet上
+func applyAuth(funclets []*Funclet, auths []string) error {
+	applied := false
+	for _, f := range funclets {
+		annot := f.HTTPAnnotation
+		if annot == nil || (annot.FuncletType != "onHandleFunclet" && annot.FuncletType != "onStaticFunclet") {
+			continue
+		}
+		if len(auths) == 1 && auths[0] == "none" {
+			annot.NoAuth = true
+		} else {
+			for _, name := range auths {
+				if !token.IsIdentifier(name) || name == "none" {
+					return errors.New("bad @auth " + strings.Join(auths, ","))
+				}
+			}
+			annot.Auths = auths
+		}
+		applied = true
+	}
+	if !applied {
+		return errors.New("@auth only support onHandleFunclet/onStaticFunclet")
+	}
+	return nil
+}
+
+// fieldCount 返回参数或返回值的个数,func(w http.ResponseWriter, r, x *http.Request)为3个
+func fieldCount(fl *ast.FieldList) int {
+	if fl == nil {
+		return 0
+	}
+	cnt := 0
+	for _, f := range fl.List {
+		if len(f.Names) == 0 {
+			cnt++
+		} else {
+			cnt += len(f.Names)
+		}
+	}
+	return cnt
+}
+
 func parseParam(s string) []string {
 	s = strings.TrimSpace(s)
 	if s == "" {
@@ -97,20 +231,49 @@ func matchHTTPAnnotation(fn *ast.FuncDecl, text string) (*Funclet, error) {
 	if len(matches) != 4 {
 		return nil, nil
 	}
-	cnt := len(fn.Type.Params.List)
+	cnt := fieldCount(fn.Type.Params)
 	param := parseParam(matches[3])
 	httpAnnot := &HTTPAnnotation{
 		FuncletType: matches[1],
 		Entry:       matches[2],
 		ParamCnt:    cnt,
 	}
+	options, err := parseOptions(text[len(matches[0]):])
+	if err != nil {
+		return nil, err
+	}
+	if err := httpAnnot.applyOptions(options); err != nil {
+		return nil, err
+	}
+	if matches[1] == "onHandleFunclet" && isJSONFunclet(fn) {
+		httpAnnot.JSON = true
+	} else if results := fn.Type.Results; matches[1] == "onAuthFunclet" && fieldCount(results) == 2 {
+		if ident, ok := results.List[len(results.List)-1].Type.(*ast.Ident); !ok || ident.Name != "error" {
+			return nil, errors.New("bad function result, only support error or (T, error)")
+		}
+		httpAnnot.ReturnErr, httpAnnot.Principal = true, true
+	} else if results != nil {
+		if matches[1] != "onHandleFunclet" && matches[1] != "onMessageFunclet" && matches[1] != "onAuthFunclet" {
+			return nil, errors.New("bad function result, " + matches[1] + " can not return values")
+		}
+		if ident, ok := results.List[0].Type.(*ast.Ident); !ok || fieldCount(results) != 1 || ident.Name != "error" {
+			return nil, errors.New("bad function result, only support error")
+		}
+		httpAnnot.ReturnErr = true
+	}
 	if matches[1] == "onAuthFunclet" {
-		if len(param) != 0 {
+		if len(param) > 1 {
 			return nil, errors.New("bad Annotation")
 		}
 		if cnt != 3 {
 			return nil, errors.New("bad function param")
 		}
+		if len(param) == 1 {
+			if !token.IsIdentifier(param[0]) || param[0] == "none" {
+				return nil, errors.New("bad auth name " + param[0])
+			}
+			httpAnnot.AuthName = param[0]
+		}
 	} else if matches[1] == "onMessageFunclet" {
 		if len(param) != 2 {
 			return nil, errors.New("bad Annotation")
@@ -121,7 +284,7 @@ func matchHTTPAnnotation(fn *ast.FuncDecl, text string) (*Funclet, error) {
 		httpAnnot.Type = param[0]
 		httpAnnot.Path = param[1]
 	} else if matches[1] == "onHandleFunclet" {
-		if len(param) != 2 {
+		if len(param) != 2 && len(param) != 3 {
 			return nil, errors.New("bad Annotation")
 		}
 		if cnt != 2 && cnt != 3 {
@@ -132,6 +295,13 @@ func matchHTTPAnnotation(fn *ast.FuncDecl, text string) (*Funclet, error) {
 		}
 		httpAnnot.Type = param[0]
 		httpAnnot.Path = param[1]
+		if len(param) == 3 {
+			methods, err := parseMethods(param[2])
+			if err != nil {
+				return nil, err
+			}
+			httpAnnot.Methods = methods
+		}
 	} else if matches[1] == "onGattEntry" {
 		if len(param) != 2 {
 			return nil, errors.New("bad Annotation")
@@ -164,6 +334,11 @@ func matchHTTPAnnotation(fn *ast.FuncDecl, text string) (*Funclet, error) {
 		httpAnnot.Path = param[1]
 		httpAnnot.ResPath = param[2]
 	}
+	if httpAnnot.FuncletType != "onGattFunclet" && httpAnnot.FuncletType != "onAuthFunclet" && httpAnnot.Entry != "msg" {
+		if _, err := parsePattern(strings.TrimRight(httpAnnot.Path, "/"), false); err != nil {
+			return nil, err
+		}
+	}
 	if httpAnnot.FuncletType == "onGattFunclet" {
 		httpAnnot.Path = strings.TrimPrefix(httpAnnot.Path, "/")
 		if httpAnnot.Path == "" {
@@ -183,22 +358,229 @@ func matchHTTPAnnotation(fn *ast.FuncDecl, text string) (*Funclet, error) {
 	return &Funclet{HTTPAnnotation: httpAnnot}, nil
 }
 
+// parseOptions 解析注解括号之后的order(1) timeout(5s)形式的选项
+func parseOptions(s string) (map[string]string, error) {
+	options := make(map[string]string)
+	for strings.TrimSpace(s) != "" {
+		m := optionRegex.FindStringSubmatch(s)
+		if m == nil {
+			return nil, errors.New("bad annotation option " + strings.TrimSpace(s))
+		}
+		if _, ok := options[m[1]]; ok {
+			return nil, errors.New("duplicate annotation option " + m[1])
+		}
+		options[m[1]] = m[2]
+		s = s[len(m[0]):]
+	}
+	return options, nil
+}
+
+// optionTypes 各注解支持的选项
+var optionTypes = map[string][]string{
+	"order":    {"onAuthFunclet"},
+	"timeout":  {"onHandleFunclet", "onMessageFunclet", "onStaticFunclet"},
+	"maxbody":  {"onHandleFunclet", "onMessageFunclet"},
+	"consumes": {"onHandleFunclet"},
+}
+
+func (annot *HTTPAnnotation) applyOptions(options map[string]string) error {
+	for name, value := range options {
+		types, ok := optionTypes[name]
+		if !ok {
+			return errors.New("unknown annotation option " + name)
+		}
+		supported := false
+		for _, t := range types {
+			supported = supported || t == annot.FuncletType
+		}
+		if !supported {
+			return errors.New("option " + name + " only support " + strings.Join(types, "/"))
+		}
+		switch name {
+		case "order":
+			order, err := strconv.Atoi(value)
+			if err != nil {
+				return errors.New("bad order " + value)
+			}
+			annot.Order, annot.HasOrder = order, true
+		case "timeout":
+			if value != "none" {
+				if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+					return errors.New("bad timeout " + value + ", must be a positive duration like 5s or none")
+				}
+			}
+			annot.Timeout = value
+		case "maxbody":
+			if value == "none" {
+				annot.MaxBody = -1
+				break
+			}
+			n, err := faas.ParseSize(value)
+			if err != nil || n == 0 {
+				return errors.New("bad maxbody " + value + ", must be a positive size like 1MB or none")
+			}
+			annot.MaxBody = n
+		case "consumes":
+			for _, t := range strings.Split(value, "|") {
+				t = strings.ToLower(strings.TrimSpace(t))
+				if _, _, err := mime.ParseMediaType(t); err != nil || !strings.Contains(t, "/") {
+					return errors.New("bad content type " + t)
+				}
+				annot.Consumes = append(annot.Consumes, t)
+			}
+		}
+	}
+	return nil
+}
+
+// isJSONFunclet 判断函数是否为func(*faas.Context, *Req) (*Resp, error)形式
+func isJSONFunclet(fn *ast.FuncDecl) bool {
+	params, results := fn.Type.Params.List, fn.Type.Results
+	if len(params) != 2 || fieldCount(fn.Type.Params) != 2 || fieldCount(results) != 2 || len(results.List) != 2 {
+		return false
+	}
+	ctx, ok := params[0].Type.(*ast.StarExpr)
+	if !ok {
+		return false
+	}
+	if sel, ok := ctx.X.(*ast.SelectorExpr); !ok || sel.Sel.Name != "Context" {
+		return false
+	}
+	if _, ok := params[1].Type.(*ast.StarExpr); !ok {
+		return false
+	}
+	if _, ok := results.List[0].Type.(*ast.StarExpr); !ok {
+		return false
+	}
+	ident, ok := results.List[1].Type.(*ast.Ident)
+	return ok && ident.Name == "error"
+}
+
 func matchTimingAnnotation(fn *ast.FuncDecl, text string) (*Funclet, error) {
 	matches := timingRegex.FindStringSubmatch(text)
 	if len(matches) < 2 {
 		return nil, nil
 	}
-	if len(fn.Type.Params.List) != 1 {
+	if fieldCount(fn.Type.Params) != 1 {
 		return nil, errors.New("func param err")
 	}
-	annotType := matches[1]
-	interval := ""
+	timingAnnot := &TimingAnnotation{Type: matches[1]}
 	if len(matches) == 3 {
-		interval = matches[2]
+		params, err := splitTimingParams(matches[2])
+		if err != nil {
+			return nil, err
+		}
+		for i, p := range params {
+			if i == 0 {
+				timingAnnot.Interval = p
+			} else if tz, ok := strings.CutPrefix(p, "tz="); ok {
+				if timingAnnot.Type != "everyday" && timingAnnot.Type != "cron" {
+					return nil, errors.New("tz only support everyday/cron")
+				}
+				if _, err := time.LoadLocation(tz); err != nil {
+					return nil, err
+				}
+				timingAnnot.TZ = tz
+			} else if policy, ok := strings.CutPrefix(p, "policy="); ok {
+				if policy != "skip" && policy != "queue" && policy != "parallel" {
+					return nil, errors.New("Error policy " + policy + ",only support skip/queue/parallel")
+				}
+				if timingAnnot.Type == "once" {
+					return nil, errors.New("policy not support once")
+				}
+				timingAnnot.Policy = policy
+			} else {
+				return nil, errors.New("unknown timing param " + p)
+			}
+		}
 	}
-	timingAnnot := &TimingAnnotation{
-		Type:     annotType,
-		Interval: interval,
+	switch timingAnnot.Type {
+	case "repeat":
+		if _, err := time.ParseDuration(timingAnnot.Interval); err != nil {
+			return nil, err
+		}
+	case "everyday":
+		if _, _, _, err := faas.ParseTimeOfDay(timingAnnot.Interval); err != nil {
+			return nil, err
+		}
+	case "cron":
+		if _, err := faas.ParseCron(timingAnnot.Interval); err != nil {
+			return nil, err
+		}
 	}
 	return &Funclet{TimingAnnotation: timingAnnot}, nil
 }
+
+// splitTimingParams 按逗号拆分定时注解参数,双引号内的逗号不拆分,引号会被去掉
+func splitTimingParams(s string) ([]string, error) {
+	var params []string
+	var cur strings.Builder
+	quoted := false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			params = append(params, strings.TrimSpace(cur.String()))
+			cur.Reset()
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	if quoted {
+		return nil, errors.New("unterminated quote in " + s)
+	}
+	return append(params, strings.TrimSpace(cur.String())), nil
+}
+
+func matchLifecycleAnnotation(fn *ast.FuncDecl, text string) (*Funclet, error) {
+	matches := lifecycleRegex.FindStringSubmatch(text)
+	if len(matches) != 3 {
+		return nil, nil
+	}
+	if fieldCount(fn.Type.Params) != 1 || fieldCount(fn.Type.Results) != 1 {
+		return nil, errors.New("bad function param, want func(ctx context.Context) error")
+	}
+	lifecycleAnnot := &LifecycleAnnotation{Type: matches[1]}
+	if matches[2] != "" {
+		order, err := strconv.Atoi(matches[2])
+		if err != nil {
+			return nil, err
+		}
+		lifecycleAnnot.Order = order
+	}
+	return &Funclet{LifecycleAnnotation: lifecycleAnnot}, nil
+}
+
+func matchMiddlewareAnnotation(fn *ast.FuncDecl, text string) (*Funclet, error) {
+	matches := middlewareRegex.FindStringSubmatch(text)
+	if len(matches) != 4 {
+		return nil, nil
+	}
+	if fieldCount(fn.Type.Params) != 1 || fieldCount(fn.Type.Results) != 1 {
+		return nil, errors.New("bad function param, want func(next http.Handler) http.Handler")
+	}
+	mwAnnot := &MiddlewareAnnotation{Entry: matches[1]}
+	param := parseParam(matches[2])
+	if len(param) != 0 {
+		if len(param) != 2 {
+			return nil, errors.New("bad Annotation")
+		}
+		if param[0] != "path" && param[0] != "prefix" {
+			return nil, errors.New("Error type " + param[0] + ",only support path/prefix")
+		}
+		mwAnnot.Type = param[0]
+		mwAnnot.Path = "/" + strings.Trim(param[1], "/")
+		if _, err := parsePattern(mwAnnot.Path, false); err != nil {
+			return nil, err
+		}
+	}
+	if matches[3] != "" {
+		order, err := strconv.Atoi(matches[3])
+		if err != nil {
+			return nil, err
+		}
+		mwAnnot.Order = order
+	}
+	return &Funclet{MiddlewareAnnotation: mwAnnot}, nil
+}
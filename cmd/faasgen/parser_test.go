@@ -0,0 +1,27 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseMethods(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"GET", "GET", true},
+		{"get|Post", "GET POST", true},
+		{" PUT | PATCH ", "PUT PATCH", true},
+		{"GET|FETCH", "", false},
+		{"GET|get", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		methods, err := parseMethods(tt.in)
+		if (err == nil) != tt.ok || strings.Join(methods, " ") != tt.want {
+			t.Errorf("parseMethods(%q) = %v, %v, want %q", tt.in, methods, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"go/token"
+	"strings"
+)
+
+// segment 路由模式中的一段,wild为{name},multi为{name...}或前缀模式结尾的/
+type segment struct {
+	lit   string
+	wild  bool
+	multi bool
+}
+
+// parsePattern 解析/users/{id}形式的路由模式,prefix为true时末尾追加一个multi段
+func parsePattern(path string, prefix bool) ([]segment, error) {
+	var segs []segment
+	names := make(map[string]bool)
+	var parts []string
+	if path != "" && path != "/" {
+		parts = strings.Split(strings.TrimPrefix(path, "/"), "/")
+	}
+	for i, part := range parts {
+		if !strings.Contains(part, "{") && !strings.Contains(part, "}") {
+			segs = append(segs, segment{lit: part})
+			continue
+		}
+		if !strings.HasPrefix(part, "{") || !strings.HasSuffix(part, "}") {
+			return nil, errors.New("bad path pattern " + path + ", wildcard must be a full segment")
+		}
+		name := part[1 : len(part)-1]
+		if name == "$" {
+			return nil, errors.New("bad path pattern " + path + ", {$} is not supported, use path type instead")
+		}
+		seg := segment{wild: true}
+		if strings.HasSuffix(name, "...") {
+			if i != len(parts)-1 || prefix {
+				return nil, errors.New("bad path pattern " + path + ", {" + name + "} must be the last segment of a path pattern")
+			}
+			name = strings.TrimSuffix(name, "...")
+			seg = segment{multi: true}
+		}
+		if !token.IsIdentifier(name) {
+			return nil, errors.New("bad path pattern " + path + ", bad wildcard name " + name)
+		}
+		if names[name] {
+			return nil, errors.New("bad path pattern " + path + ", duplicate wildcard name " + name)
+		}
+		names[name] = true
+		segs = append(segs, seg)
+	}
+	if prefix {
+		segs = append(segs, segment{multi: true})
+	}
+	return segs, nil
+}
+
+type relation int
+
+const (
+	disjoint     relation = iota // 没有同时匹配的路径
+	equivalent                   // 匹配的路径完全相同
+	moreSpecific                 // 第一个模式匹配的路径是第二个的子集
+	moreGeneral                  // 第一个模式匹配的路径是第二个的超集
+	overlaps                     // 有同时匹配的路径但互不包含
+)
+
+// comparePatterns 按net/http.ServeMux的规则比较两个模式,equivalent和overlaps在注册时会冲突
+func comparePatterns(a, b []segment) relation {
+	aMore, bMore := false, false
+	for i := 0; ; i++ {
+		if i == len(a) || i == len(b) {
+			if len(a) != len(b) {
+				return disjoint
+			}
+			break
+		}
+		sa, sb := a[i], b[i]
+		if sa.multi || sb.multi {
+			// multi匹配剩余的至少一段,两边在此处都还有段,因此必有交集
+			if !sa.multi {
+				aMore = true
+			} else if !sb.multi {
+				bMore = true
+			}
+			break
+		}
+		switch {
+		case sa.wild && sb.wild:
+		case sa.wild:
+			bMore = true
+		case sb.wild:
+			aMore = true
+		case sa.lit != sb.lit:
+			return disjoint
+		}
+	}
+	switch {
+	case aMore && bMore:
+		return overlaps
+	case aMore:
+		return moreSpecific
+	case bMore:
+		return moreGeneral
+	}
+	return equivalent
+}
+
+// routePattern 返回注册到ServeMux的模式字符串,前缀模式以/结尾
+func routePattern(annot *HTTPAnnotation) string {
+	if annot.Type == "prefix" && annot.Path != "/" {
+		return annot.Path + "/"
+	}
+	return annot.Path
+}
+
+// checkPatternConflicts 检查同一入口下不同的路由模式是否会在ServeMux注册时冲突,
+// 相同模式按方法分发,已由pathMap/prefixMap检查
+func checkPatternConflicts(funclets []*Funclet) error {
+	type registered struct {
+		pattern string
+		segs    []segment
+		owner   string
+	}
+	byEntry := make(map[string][]registered)
+	for _, f := range funclets {
+		if f == nil || f.HTTPAnnotation == nil {
+			continue
+		}
+		annot := f.HTTPAnnotation
+		if annot.Entry == "msg" || annot.FuncletType == "onGattFunclet" || annot.FuncletType == "onAuthFunclet" {
+			continue
+		}
+		patterns := []string{routePattern(annot)}
+		if annot.FuncletType == "onGattEntry" {
+			patterns = append(patterns, annot.Path)
+		}
+		for _, pattern := range patterns {
+			segs, err := parsePattern(strings.TrimSuffix(pattern, "/"), strings.HasSuffix(pattern, "/"))
+			if err != nil {
+				return err
+			}
+			owner := f.ImportPath + "@" + f.Name
+			for _, r := range byEntry[annot.Entry] {
+				if r.pattern == pattern {
+					continue
+				}
+				rel := comparePatterns(segs, r.segs)
+				if rel == equivalent || rel == overlaps {
+					return errors.New("path pattern conflict: " + pattern + " " + r.pattern + "   " + r.owner + "  <------>  " + owner)
+				}
+			}
+			byEntry[annot.Entry] = append(byEntry[annot.Entry], registered{pattern, segs, owner})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestParsePattern(t *testing.T) {
+	tests := []struct {
+		path string
+		err  string
+	}{
+		{"/users/{id}", ""},
+		{"/users/{id}/posts/{post}", ""},
+		{"/files/{path...}", ""},
+		{"/", ""},
+		{"/users/id{id}", "wildcard must be a full segment"},
+		{"/users/{id", "wildcard must be a full segment"},
+		{"/users/{$}", "{$} is not supported"},
+		{"/files/{path...}/x", "must be the last segment"},
+		{"/users/{1id}", "bad wildcard name"},
+		{"/users/{id}/{id}", "duplicate wildcard name"},
+	}
+	for _, tt := range tests {
+		_, err := parsePattern(tt.path, false)
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("parsePattern(%q) error = %v, want %q", tt.path, err, tt.err)
+		}
+	}
+	if _, err := parsePattern("/files/{path...}", true); err == nil {
+		t.Errorf("parsePattern accepts {path...} in a prefix pattern")
+	}
+}
+
+func TestComparePatterns(t *testing.T) {
+	tests := []struct {
+		a, b string
+		rel  relation
+	}{
+		{"/users/{id}", "/users/{name}", equivalent},
+		{"/users/me", "/users/{id}", moreSpecific},
+		{"/users/{id}", "/users/me", moreGeneral},
+		{"/users/{id}/posts", "/users/me/{x}", overlaps},
+		{"/{a}/b", "/a/{b}", overlaps},
+		{"/users/{id}", "/posts/{id}", disjoint},
+		{"/users/{id}", "/users/{id}/posts", disjoint},
+		{"/files/{path...}", "/files/a/b", moreGeneral},
+		{"/files/{path...}", "/files/{name}", moreGeneral},
+		{"/files/", "/files/{path...}", equivalent},
+		{"/files/", "/{dir}/a", overlaps},
+		{"/files/", "/files", disjoint},
+		{"/a/{x}/", "/{y}/b/", overlaps},
+	}
+	for _, tt := range tests {
+		a, err := parsePattern(strings.TrimSuffix(tt.a, "/"), strings.HasSuffix(tt.a, "/"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := parsePattern(strings.TrimSuffix(tt.b, "/"), strings.HasSuffix(tt.b, "/"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := comparePatterns(a, b); got != tt.rel {
+			t.Errorf("comparePatterns(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.rel)
+		}
+		// 与ServeMux注册时的冲突检查一致
+		conflict := tt.rel == equivalent || tt.rel == overlaps
+		if panicked := registerBoth(tt.a, tt.b); panicked != conflict {
+			t.Errorf("ServeMux conflict for %q and %q = %v, want %v", tt.a, tt.b, panicked, conflict)
+		}
+	}
+}
+
+func registerBoth(a, b string) (panicked bool) {
+	defer func() {
+		panicked = recover() != nil
+	}()
+	mux := http.NewServeMux()
+	mux.HandleFunc(a, func(http.ResponseWriter, *http.Request) {})
+	mux.HandleFunc(b, func(http.ResponseWriter, *http.Request) {})
+	return false
+}
+
+func handleFunclet(name, entry, typ, path string, methods ...string) *Funclet {
+	return &Funclet{Name: name, HTTPAnnotation: &HTTPAnnotation{
+		FuncletType: "onHandleFunclet",
+		Entry:       entry,
+		Type:        typ,
+		Path:        path,
+		Methods:     methods,
+	}}
+}
+
+func TestBuildTemplateDataConflicts(t *testing.T) {
+	tests := []struct {
+		name     string
+		funclets []*Funclet
+		err      string
+	}{
+		{"methods on the same path", []*Funclet{
+			handleFunclet("Get", "api", "path", "/users/{id}", "GET"),
+			handleFunclet("Put", "api", "path", "/users/{id}", "PUT", "PATCH"),
+			handleFunclet("Any", "api", "path", "/users/{id}"),
+		}, ""},
+		{"duplicate method", []*Funclet{
+			handleFunclet("Get", "api", "path", "/users/{id}", "GET", "POST"),
+			handleFunclet("Post", "api", "path", "/users/{id}", "POST"),
+		}, "path pattern conflict: POST /users/{id}"},
+		{"duplicate any method", []*Funclet{
+			handleFunclet("A", "api", "path", "/users"),
+			handleFunclet("B", "api", "path", "/users"),
+		}, "path pattern conflict: * /users"},
+		{"duplicate prefix method", []*Funclet{
+			handleFunclet("A", "api", "prefix", "/static", "GET"),
+			handleFunclet("B", "api", "prefix", "/static", "GET"),
+		}, "prefix pattern conflict: GET /static"},
+		{"different entries", []*Funclet{
+			handleFunclet("A", "api", "path", "/users/{id}"),
+			handleFunclet("B", "local", "path", "/users/{name}"),
+		}, ""},
+		{"more specific", []*Funclet{
+			handleFunclet("A", "api", "path", "/users/{id}"),
+			handleFunclet("B", "api", "path", "/users/me"),
+			handleFunclet("C", "api", "prefix", "/users"),
+		}, ""},
+		{"equivalent wildcards", []*Funclet{
+			handleFunclet("A", "api", "path", "/users/{id}", "GET"),
+			handleFunclet("B", "api", "path", "/users/{name}", "POST"),
+		}, "path pattern conflict: /users/{name} /users/{id}"},
+		{"overlapping wildcards", []*Funclet{
+			handleFunclet("A", "api", "path", "/{kind}/list"),
+			handleFunclet("B", "api", "path", "/users/{id}"),
+		}, "path pattern conflict"},
+		{"prefix and multi wildcard", []*Funclet{
+			handleFunclet("A", "api", "prefix", "/files"),
+			handleFunclet("B", "api", "path", "/files/{path...}"),
+		}, "path pattern conflict"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := buildTemplateData(tt.funclets, "")
+			if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("buildTemplateData error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
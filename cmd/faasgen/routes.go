@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/faasteam/faas"
+)
+
+// routesCommand 实现faasgen routes,按运行时路由表的格式输出注解中的路由
+func routesCommand(args []string) {
+	fs := flag.NewFlagSet("routes", flag.ExitOnError)
+	src := fs.String("src", "", "Source file or directory to scan for annotations.")
+	format := fs.String("format", "text", "Output format, text or json.")
+	fs.Parse(args)
+
+	funclets, _ := scanFunclets(*src)
+	data, err := buildTemplateData(funclets, "")
+	if err != nil {
+		log.Fatalf("Error checking funclets: %v", err)
+	}
+	table := routeTable(data)
+	switch *format {
+	case "text":
+		err = table.WriteText(os.Stdout)
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(table)
+	default:
+		log.Fatalf("Unknown format %s, only support text/json", *format)
+	}
+	if err != nil {
+		log.Fatalf("Error writing routes: %v", err)
+	}
+}
+
+// routeTable 将生成代码会注册的内容转换为运行时的路由表
+func routeTable(data *TemplateData) faas.RouteTable {
+	entries := make(map[string]*faas.EntryInfo)
+	entry := func(name string) *faas.EntryInfo {
+		e, ok := entries[name]
+		if !ok {
+			e = &faas.EntryInfo{Name: name, Routes: []faas.RouteInfo{}}
+			entries[name] = e
+		}
+		return e
+	}
+	for _, f := range data.AuthFunclets {
+		entry(f.HTTPAnnotation.Entry).Auths++
+	}
+	for _, f := range data.Middlewares {
+		entry(f.MiddlewareAnnotation.Entry)
+	}
+	for _, f := range data.HTTPFunclets {
+		e := entry(f.HTTPAnnotation.Entry)
+		e.Routes = append(e.Routes, routeInfo(f))
+	}
+	if f := data.GattEntry; f != nil {
+		e := entry(f.HTTPAnnotation.Entry)
+		for _, typ := range []string{"path", "prefix"} {
+			e.Routes = append(e.Routes, faas.RouteInfo{Type: typ, Path: routePath(typ, f.HTTPAnnotation.Path), Handler: funcletName(f)})
+		}
+	}
+
+	var table faas.RouteTable
+	for _, e := range entries {
+		for i := range e.Routes {
+			r := &e.Routes[i]
+			if r.Auth == "" {
+				r.Auth, r.Auths = "entry", e.Auths
+			}
+			if r.Auths == 0 {
+				r.Auth = "none"
+			}
+		}
+		table.Entries = append(table.Entries, *e)
+	}
+	for _, f := range data.GattFunclets {
+		table.GattFns = append(table.GattFns, f.HTTPAnnotation.Path)
+	}
+	for _, f := range data.TimingFunclets {
+		annot := f.TimingAnnotation
+		policy := annot.Policy
+		if policy == "" {
+			policy = string(faas.ConcurrencyQueue)
+		}
+		table.Timings = append(table.Timings, faas.TimingInfo{
+			Name:     funcletName(f),
+			Type:     annot.Type,
+			Interval: annot.Interval,
+			Policy:   policy,
+			TimeZone: annot.TZ,
+		})
+	}
+	table.Sort()
+	return table
+}
+
+// routeInfo 返回HTTP funclet注册的路由,Auth为空时使用入口的鉴权链
+func routeInfo(f *Funclet) faas.RouteInfo {
+	annot := f.HTTPAnnotation
+	info := faas.RouteInfo{
+		Type:         annot.Type,
+		Path:         annot.Path,
+		MaxBodyBytes: annot.MaxBody,
+		Handler:      funcletName(f),
+	}
+	if annot.Entry != "msg" {
+		info.Path = routePath(annot.Type, annot.Path)
+	}
+	info.Methods = append(info.Methods, annot.Methods...)
+	sort.Strings(info.Methods)
+	if annot.NoAuth {
+		info.Auth = "none"
+	} else if len(annot.Auths) != 0 {
+		info.Auth, info.Auths = "route", len(annot.Auths)
+	}
+	if annot.Timeout == "none" {
+		info.Timeout = "none"
+	} else if d, err := time.ParseDuration(annot.Timeout); err == nil && d > 0 {
+		info.Timeout = d.String()
+	}
+	for _, t := range annot.Consumes {
+		info.ContentTypes = append(info.ContentTypes, strings.ToLower(t))
+	}
+	return info
+}
+
+// routePath 返回运行时注册的路由模式,前缀模式以/结尾
+func routePath(typ, path string) string {
+	if typ == "prefix" && !strings.HasSuffix(path, "/") {
+		return path + "/"
+	}
+	return path
+}
+
+// funcletName 返回与运行时相同格式的函数名,如example/server.Ping,生成代码所在包的函数为main.Ping
+func funcletName(f *Funclet) string {
+	if f.ImportPath == "" {
+		return "main." + f.Name
+	}
+	return f.ImportPath + "." + f.Name
+}
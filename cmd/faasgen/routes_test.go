@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/faasteam/faas"
+)
+
+// TestRouteTableMatchesRuntime faasgen routes与运行时的路由表除HANDLER外一致
+func TestRouteTableMatchesRuntime(t *testing.T) {
+	auth := &Funclet{Name: "Auth", HTTPAnnotation: &HTTPAnnotation{FuncletType: "onAuthFunclet", Entry: "api"}}
+	health := handleFunclet("Health", "api", "path", "/health")
+	health.HTTPAnnotation.NoAuth = true
+	slow := handleFunclet("Slow", "local", "path", "/a")
+	slow.HTTPAnnotation.Timeout = "5s"
+	data, err := buildTemplateData([]*Funclet{
+		handleFunclet("Update", "api", "path", "/users/{id}", "PUT", "PATCH"),
+		handleFunclet("Static", "api", "prefix", "/static"),
+		handleFunclet("B", "local", "path", "/b"),
+		slow,
+		health,
+		handleFunclet("Get", "api", "path", "/users/{id}", "GET"),
+		auth,
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := routeTable(data)
+
+	app := faas.New()
+	h := func(http.ResponseWriter, *http.Request) {}
+	app.HandleFunc("local", "path", "/b", h)
+	app.HandleFunc("api", "path", "/users/{id}", h, faas.Methods("GET"))
+	app.HandleFunc("api", "path", "/health", h, faas.NoAuth())
+	app.HandleFunc("api", "path", "/static", h)
+	app.HandleFunc("api", "prefix", "/static", h)
+	app.HandleFunc("local", "path", "/a", h, faas.Timeout(5*time.Second))
+	app.HandleFunc("api", "path", "/users/{id}", h, faas.Methods("PUT", "PATCH"))
+	app.HandleAuthFunc("api", func(http.ResponseWriter, *http.Request, *faas.Context) error { return nil })
+	want := app.Routes()
+
+	for _, e := range got.Entries {
+		for i := range e.Routes {
+			if e.Routes[i].Handler == "" {
+				t.Errorf("%s %s has no handler", e.Name, e.Routes[i].Path)
+			}
+			e.Routes[i].Handler = ""
+		}
+	}
+	gotJSON, _ := json.MarshalIndent(got, "", "  ")
+	wantJSON, _ := json.MarshalIndent(want, "", "  ")
+	if string(gotJSON) != string(wantJSON) {
+		t.Errorf("faasgen route table:\n%s\nruntime route table:\n%s", gotJSON, wantJSON)
+	}
+}
+
+func TestFuncletName(t *testing.T) {
+	tests := []struct {
+		importPath, want string
+	}{
+		{"", "main.Ping"},
+		{"example/server", "example/server.Ping"},
+	}
+	for _, tt := range tests {
+		if got := funcletName(&Funclet{Name: "Ping", ImportPath: tt.importPath}); got != tt.want {
+			t.Errorf("funcletName(%q) = %q, want %q", tt.importPath, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/faasteam/faas"
+)
+
+// Audit 创建和修改信息
+type Audit struct {
+	// 创建时间
+	Created time.Time  `json:"created"`
+	Updated *time.Time `json:"updated,omitempty"`
+}
+
+// Address 地址
+type Address struct {
+	City   string `json:"city"`
+	Street string `json:"street,omitempty"` // 街道和门牌号
+	Zip    *int32 `json:"zip,omitempty"`
+}
+
+// Role 用户角色
+type Role string
+
+// User 用户
+type User struct {
+	Audit
+	ID int64 `json:"id,string"`
+	// 用户名,唯一
+	Name    string            `json:"name"`
+	Email   *string           `json:"email,omitempty"`
+	Roles   []Role            `json:"roles"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Address *Address          `json:"address,omitempty"`
+	Friends []*User           `json:"friends,omitempty"`
+	Avatar  []byte            `json:"avatar,omitempty"`
+	Score   float64
+	Secret  string `json:"-"`
+	token   string
+}
+
+// GetUserReq 查询用户
+type GetUserReq struct {
+	// 用户ID
+	ID int64 `path:"id"`
+	// 返回的字段,多个时重复
+	Fields  []string `query:"fields"`
+	Verbose bool     `query:"verbose"`
+}
+
+// UpdateUserReq 修改用户
+type UpdateUserReq struct {
+	ID     int64             `path:"id"`
+	DryRun bool              `query:"dry_run"`
+	Name   *string           `json:"name,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// ListUsersResp 用户列表
+type ListUsersResp struct {
+	Users []User `json:"users"`
+	// 下一页的游标,没有更多时为空
+	Next  string `json:"next,omitempty"`
+	Total uint   `json:"total"`
+}
+
+// @onAuthFunclet api()
+func Auth(w http.ResponseWriter, r *http.Request, c *faas.Context) error { return nil }
+
+// GetUser 查询用户
+// 按ID返回用户,不存在时返回404
+// @onHandleFunclet api(path, /users/{id}, GET)
+func GetUser(c *faas.Context, req *GetUserReq) (*User, error) { return nil, nil }
+
+// UpdateUser 修改用户
+// @onHandleFunclet api(path, /users/{id}, PUT|PATCH)
+func UpdateUser(c *faas.Context, req *UpdateUserReq) (*User, error) { return nil, nil }
+
+// CreateUser 创建用户
+// @onHandleFunclet api(path, /users)
+func CreateUser(c *faas.Context, req *User) (*User, error) { return nil, nil }
+
+// ListUsers 用户列表
+// @onHandleFunclet api(path, /users, GET)
+// @auth none
+func ListUsers(c *faas.Context, req *struct{}) (*ListUsersResp, error) { return nil, nil }
+
+// File 下载文件
+// @onHandleFunclet api(path, /files/{name...}, GET)
+func File(w http.ResponseWriter, r *http.Request) {}
+
+// Static 静态资源
+// @onHandleFunclet api(prefix, /static)
+// @auth none
+func Static(w http.ResponseWriter, r *http.Request) {}
+
+// Upload 上传文件
+// @onHandleFunclet api(path, /upload, POST) consumes(image/png|image/jpeg)
+func Upload(w http.ResponseWriter, r *http.Request) error { return nil }
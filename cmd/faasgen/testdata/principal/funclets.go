@@ -0,0 +1,59 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/faasteam/faas"
+)
+
+type User struct {
+	Name string
+}
+
+type Admin struct {
+	Name string
+}
+
+// @onAuthFunclet api()
+func Auth(w http.ResponseWriter, r *http.Request, c *faas.Context) (*User, error) {
+	return &User{}, nil
+}
+
+// @onAuthFunclet api(admin)
+func AdminAuth(w http.ResponseWriter, r *http.Request, c *faas.Context) (*Admin, error) {
+	return &Admin{}, nil
+}
+
+// @onAuthFunclet local()
+func LegacyAuth(w http.ResponseWriter, r *http.Request, c *faas.Context) {}
+
+// @onHandleFunclet api(path, /me)
+func Me(w http.ResponseWriter, r *http.Request, c *faas.Context) {
+	faas.Principal[*User](c)
+}
+
+// @onHandleFunclet api(path, /value)
+func Value(w http.ResponseWriter, r *http.Request, c *faas.Context) {
+	if user, ok := faas.Principal[User](c); ok {
+		w.Write([]byte(user.Name))
+	}
+}
+
+// @onHandleFunclet api(path, /admin)
+// @auth admin
+func AdminOnly(w http.ResponseWriter, r *http.Request, c *faas.Context) {
+	faas.Principal[*Admin](c)
+	faas.Principal[*User](c)
+}
+
+// @onHandleFunclet api(path, /public)
+// @auth none
+func Public(w http.ResponseWriter, r *http.Request, c *faas.Context) {
+	faas.Principal[*User](c)
+}
+
+// 鉴权链中有未声明类型的鉴权funclet时不检查
+// @onHandleFunclet local(path, /legacy)
+func Legacy(w http.ResponseWriter, r *http.Request, c *faas.Context) {
+	faas.Principal[int](c)
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/faasteam/faas"
+)
+
+// @onHandleFunclet api(path, /two-requests)
+func TwoRequests(w http.ResponseWriter, r, x *http.Request) {}
+
+// @onHandleFunclet api(path, /value-context)
+func ValueContext(w http.ResponseWriter, r *http.Request, c faas.Context) {}
+
+// @onHandleFunclet api(path, /variadic)
+func Variadic(w http.ResponseWriter, rs ...*http.Request) {}
+
+// @onHandleFunclet api(path, /generic)
+func Generic[T any](w http.ResponseWriter, r *http.Request) {}
+
+// @onAuthFunclet api()
+func SwappedAuth(r *http.Request, w http.ResponseWriter, c *faas.Context) error { return nil }
+
+// @onMessageFunclet msg(queue, /orders)
+func BytesMessage(msg []byte) {}
+
+// @onTimingFunclet time(repeat, 5s)
+func StringEnv(env map[string]string) {}
+
+// @onStartFunclet
+func StringStart(ctx string) error { return nil }
+
+// @onStopFunclet
+func NoError(ctx context.Context) bool { return true }
+
+// @onMiddleware api()
+func FuncMiddleware(next http.HandlerFunc) http.Handler { return next }
@@ -0,0 +1,8 @@
+package server
+
+import "net/http"
+
+// @onHandleFunclet api(path, /broken)
+func Broken(w http.ResponseWriter, r *http.Request) {
+	w.Write(body)
+}
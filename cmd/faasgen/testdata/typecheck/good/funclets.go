@@ -0,0 +1,46 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/faasteam/faas"
+)
+
+type User struct {
+	Name string
+}
+
+type Req struct{}
+
+type Resp struct{}
+
+// @onHandleFunclet api(path, /plain)
+func Plain(w http.ResponseWriter, r *http.Request) {}
+
+// @onHandleFunclet api(path, /err)
+func Err(w http.ResponseWriter, r *http.Request) error { return nil }
+
+// @onHandleFunclet api(path, /ctx)
+func Ctx(w http.ResponseWriter, r *http.Request, c *faas.Context) {}
+
+// @onHandleFunclet api(path, /ctxerr)
+func CtxErr(w http.ResponseWriter, r *http.Request, c *faas.Context) error { return nil }
+
+// @onHandleFunclet api(path, /json)
+func JSON(c *faas.Context, req *Req) (*Resp, error) { return nil, nil }
+
+// @onAuthFunclet api()
+func Auth(w http.ResponseWriter, r *http.Request, c *faas.Context) (*User, error) { return nil, nil }
+
+// @onMessageFunclet msg(queue, /orders)
+func Message(msg string) error { return nil }
+
+// @onTimingFunclet time(repeat, 5s)
+func Tick(env map[string]any) {}
+
+// @onStartFunclet
+func Start(ctx context.Context) error { return nil }
+
+// @onMiddleware api()
+func Middleware(next http.Handler) http.Handler { return next }
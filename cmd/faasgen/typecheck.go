@@ -0,0 +1,362 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	faasPkg     = "github.com/faasteam/faas"
+	writerType  = "net/http.ResponseWriter"
+	requestType = "*net/http.Request"
+	contextType = "*" + faasPkg + ".Context"
+)
+
+// signature 描述一种funclet允许的函数签名,params和results为types.TypeString的结果
+type signature struct {
+	params  []string
+	results []string
+}
+
+func (s signature) String() string {
+	short := func(list []string) string {
+		for i, t := range list {
+			t = strings.ReplaceAll(t, "net/http.", "http.")
+			t = strings.ReplaceAll(t, faasPkg+".", "faas.")
+			list[i] = t
+		}
+		return strings.Join(list, ", ")
+	}
+	params := short(append([]string(nil), s.params...))
+	results := short(append([]string(nil), s.results...))
+	if len(s.results) > 1 {
+		results = "(" + results + ")"
+	}
+	return strings.TrimSpace("func(" + params + ") " + results)
+}
+
+var (
+	contextSignature    = signature{params: []string{writerType, requestType, contextType}}
+	timingSignature     = signature{params: []string{"map[string]any"}}
+	hookSignature       = signature{params: []string{"context.Context"}, results: []string{"error"}}
+	middlewareSignature = signature{params: []string{"net/http.Handler"}, results: []string{"net/http.Handler"}}
+)
+
+// typeChecker 对funclet所在的包做类型检查,所有包共用一个importer以复用已加载的依赖
+type typeChecker struct {
+	fset     *token.FileSet
+	importer types.Importer
+	pkgs     map[string]*checkedPackage
+	//生成的文件,类型检查时跳过
+	output string
+}
+
+type checkedPackage struct {
+	*types.Package
+	files []*ast.File
+	info  *types.Info
+}
+
+func newTypeChecker(output string) *typeChecker {
+	fset := token.NewFileSet()
+	return &typeChecker{
+		fset:     fset,
+		importer: importer.ForCompiler(fset, "source", nil),
+		pkgs:     make(map[string]*checkedPackage),
+		output:   filepath.Clean(output),
+	}
+}
+
+// checkFunclets 按注解类型检查funclet的函数签名,返回带file:line的诊断信息
+func (tc *typeChecker) checkFunclets(funclets []*Funclet) error {
+	sorted := append([]*Funclet(nil), funclets...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i].Pos, sorted[j].Pos
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		return a.Line < b.Line
+	})
+	var diags []string
+	for _, f := range sorted {
+		pkg, err := tc.load(f.Dir, f.ImportPath)
+		if err != nil {
+			return err
+		}
+		if msg := checkSignature(pkg.Package, f); msg != "" {
+			diags = append(diags, f.Pos.String()+": func "+f.Name+": "+msg)
+		}
+	}
+	if len(diags) == 0 {
+		diags = tc.checkPrincipals(sorted)
+	}
+	if len(diags) != 0 {
+		return errors.New(strings.Join(diags, "\n"))
+	}
+	return nil
+}
+
+func (tc *typeChecker) load(dir, importPath string) (*checkedPackage, error) {
+	if pkg, ok := tc.pkgs[dir]; ok {
+		return pkg, nil
+	}
+	bp, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	var files []*ast.File
+	for _, name := range bp.GoFiles {
+		path := filepath.Join(dir, name)
+		if path == tc.output {
+			continue
+		}
+		file, err := parser.ParseFile(tc.fset, path, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	var typeErrs []string
+	info := &types.Info{
+		Uses:      make(map[*ast.Ident]types.Object),
+		Instances: make(map[*ast.Ident]types.Instance),
+	}
+	conf := types.Config{
+		Importer: tc.importer,
+		Error: func(err error) {
+			typeErrs = append(typeErrs, err.Error())
+		},
+	}
+	if importPath == "" {
+		importPath = "main"
+	}
+	pkg, _ := conf.Check(importPath, tc.fset, files, info)
+	if len(typeErrs) != 0 {
+		return nil, errors.New(strings.Join(typeErrs, "\n"))
+	}
+	checked := &checkedPackage{Package: pkg, files: files, info: info}
+	tc.pkgs[dir] = checked
+	return checked, nil
+}
+
+// allowedSignatures 返回注解类型允许的函数签名
+func allowedSignatures(f *Funclet) []signature {
+	switch {
+	case f.TimingAnnotation != nil:
+		return []signature{timingSignature}
+	case f.LifecycleAnnotation != nil:
+		return []signature{hookSignature}
+	case f.MiddlewareAnnotation != nil:
+		return []signature{middlewareSignature}
+	}
+	switch f.HTTPAnnotation.FuncletType {
+	case "onHandleFunclet":
+		return []signature{
+			{params: []string{writerType, requestType}},
+			{params: []string{writerType, requestType}, results: []string{"error"}},
+			contextSignature,
+			{params: []string{writerType, requestType, contextType}, results: []string{"error"}},
+			{params: []string{contextType, "*Req"}, results: []string{"*Resp", "error"}},
+		}
+	case "onAuthFunclet":
+		return []signature{
+			contextSignature,
+			{params: []string{writerType, requestType, contextType}, results: []string{"error"}},
+			{params: []string{writerType, requestType, contextType}, results: []string{"T", "error"}},
+		}
+	case "onMessageFunclet":
+		return []signature{
+			{params: []string{"string"}},
+			{params: []string{"string"}, results: []string{"error"}},
+		}
+	}
+	return []signature{contextSignature}
+}
+
+func checkSignature(pkg *types.Package, f *Funclet) string {
+	obj, ok := pkg.Scope().Lookup(f.Name).(*types.Func)
+	if !ok {
+		return "not a package-level function"
+	}
+	sig := obj.Type().(*types.Signature)
+	if sig.TypeParams() != nil {
+		return "funclet can not be generic"
+	}
+	allowed := allowedSignatures(f)
+	for _, want := range allowed {
+		if matchSignature(sig, want) {
+			return ""
+		}
+	}
+	wants := make([]string, len(allowed))
+	for i, want := range allowed {
+		wants[i] = want.String()
+	}
+	got := types.TypeString(sig, func(p *types.Package) string {
+		if p == pkg {
+			return ""
+		}
+		return p.Name()
+	})
+	return fmt.Sprintf("signature %s does not match annotation, want %s", got, strings.Join(wants, " or "))
+}
+
+func matchSignature(sig *types.Signature, want signature) bool {
+	if sig.Variadic() || sig.Params().Len() != len(want.params) || sig.Results().Len() != len(want.results) {
+		return false
+	}
+	for i, t := range want.params {
+		if !matchType(sig.Params().At(i).Type(), t) {
+			return false
+		}
+	}
+	for i, t := range want.results {
+		if !matchType(sig.Results().At(i).Type(), t) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchType 比较类型,*Req和*Resp匹配任意指针
+func matchType(t types.Type, want string) bool {
+	switch want {
+	case "T":
+		return true
+	case "*Req", "*Resp":
+		_, ok := types.Unalias(t).(*types.Pointer)
+		return ok
+	case "map[string]any":
+		m, ok := types.Unalias(t).(*types.Map)
+		if !ok {
+			return false
+		}
+		return types.Identical(m.Key(), types.Typ[types.String]) && types.Identical(m.Elem(), types.Universe.Lookup("any").Type())
+	}
+	return types.TypeString(types.Unalias(t), nil) == want
+}
+
+// checkPrincipals 检查funclet中faas.Principal[T]的T与路由鉴权链中返回(T, error)的鉴权funclet一致,
+// 路由没有鉴权时Principal永远取不到值;鉴权链中有未声明类型的鉴权funclet时无法判断,跳过检查。
+// 只检查funclet函数体中直接的调用
+func (tc *typeChecker) checkPrincipals(funclets []*Funclet) []string {
+	chains := make(map[string][]*Funclet)
+	named := make(map[string]*Funclet)
+	for _, f := range funclets {
+		annot := f.HTTPAnnotation
+		if annot == nil || annot.FuncletType != "onAuthFunclet" {
+			continue
+		}
+		if annot.AuthName == "" {
+			chains[annot.Entry] = append(chains[annot.Entry], f)
+		} else {
+			named[annot.Entry+" "+annot.AuthName] = f
+		}
+	}
+	var diags []string
+	for _, f := range funclets {
+		annot := f.HTTPAnnotation
+		if annot == nil || annot.FuncletType == "onAuthFunclet" {
+			continue
+		}
+		pkg := tc.pkgs[f.Dir]
+		uses := principalUses(pkg, f.Name)
+		if len(uses) == 0 {
+			continue
+		}
+		auths := chains[annot.Entry]
+		if annot.NoAuth {
+			auths = nil
+		} else if len(annot.Auths) != 0 {
+			auths = nil
+			for _, name := range annot.Auths {
+				if auth, ok := named[annot.Entry+" "+name]; ok {
+					auths = append(auths, auth)
+				}
+			}
+		}
+		var have []types.Type
+		typed := true
+		for _, auth := range auths {
+			if !auth.HTTPAnnotation.Principal {
+				typed = false
+				break
+			}
+			sig := tc.pkgs[auth.Dir].Scope().Lookup(auth.Name).Type().(*types.Signature)
+			have = append(have, sig.Results().At(0).Type())
+		}
+		if !typed {
+			continue
+		}
+		qualifier := func(p *types.Package) string {
+			if p == pkg.Package {
+				return ""
+			}
+			return p.Name()
+		}
+		for _, use := range uses {
+			pos := tc.fset.Position(use.pos)
+			if len(auths) == 0 {
+				diags = append(diags, pos.String()+": func "+f.Name+": faas.Principal["+types.TypeString(use.typ, qualifier)+"] is never set, route has no auth funclet")
+				continue
+			}
+			found := false
+			var names []string
+			for _, t := range have {
+				if types.Identical(t, use.typ) {
+					found = true
+				}
+				names = append(names, types.TypeString(t, qualifier))
+			}
+			if !found {
+				diags = append(diags, pos.String()+": func "+f.Name+": faas.Principal["+types.TypeString(use.typ, qualifier)+"] does not match auth principal "+strings.Join(names, " or "))
+			}
+		}
+	}
+	return diags
+}
+
+type principalUse struct {
+	pos token.Pos
+	typ types.Type
+}
+
+// principalUses 返回函数体中faas.Principal[T]调用的位置和T
+func principalUses(pkg *checkedPackage, name string) []principalUse {
+	var body *ast.BlockStmt
+	for _, file := range pkg.files {
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil && fn.Name.Name == name {
+				body = fn.Body
+			}
+		}
+	}
+	if body == nil {
+		return nil
+	}
+	var uses []principalUse
+	ast.Inspect(body, func(n ast.Node) bool {
+		ident, ok := n.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		obj := pkg.info.Uses[ident]
+		if obj == nil || obj.Pkg() == nil || obj.Pkg().Path() != faasPkg || obj.Name() != "Principal" {
+			return true
+		}
+		if inst, ok := pkg.info.Instances[ident]; ok && inst.TypeArgs.Len() == 1 {
+			uses = append(uses, principalUse{ident.Pos(), inst.TypeArgs.At(0)})
+		}
+		return true
+	})
+	return uses
+}
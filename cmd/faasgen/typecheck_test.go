@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fixtureChecker 各测试共用,避免重复从源码加载net/http等依赖
+var fixtureChecker = newTypeChecker("")
+
+// parseFixture 解析testdata中的funclet文件
+func parseFixture(t *testing.T, path string) []*Funclet {
+	t.Helper()
+	funclets, err := parseFile(path, "github.com/faasteam/faas/cmd/faasgen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return funclets
+}
+
+func TestCheckFuncletsValid(t *testing.T) {
+	funclets := parseFixture(t, filepath.Join("testdata", "typecheck", "good", "funclets.go"))
+	if len(funclets) != 10 {
+		t.Fatalf("parsed %d funclets, want 10", len(funclets))
+	}
+	if err := fixtureChecker.checkFunclets(funclets); err != nil {
+		t.Errorf("checkFunclets: %v", err)
+	}
+}
+
+func TestCheckFuncletsInvalid(t *testing.T) {
+	path := filepath.Join("testdata", "typecheck", "bad", "funclets.go")
+	handle := "want func(http.ResponseWriter, *http.Request) or func(http.ResponseWriter, *http.Request) error" +
+		" or func(http.ResponseWriter, *http.Request, *faas.Context) or func(http.ResponseWriter, *http.Request, *faas.Context) error" +
+		" or func(*faas.Context, *Req) (*Resp, error)"
+	want := []string{
+		// 两个参数共用一个类型时按参数个数而不是字段个数检查
+		path + ":11:1: func TwoRequests: signature func(w http.ResponseWriter, r *http.Request, x *http.Request) does not match annotation, " + handle,
+		path + ":14:1: func ValueContext: signature func(w http.ResponseWriter, r *http.Request, c faas.Context) does not match annotation, " + handle,
+		path + ":17:1: func Variadic: signature func(w http.ResponseWriter, rs ...*http.Request) does not match annotation, " + handle,
+		path + ":20:1: func Generic: funclet can not be generic",
+		path + ":23:1: func SwappedAuth: signature func(r *http.Request, w http.ResponseWriter, c *faas.Context) error does not match annotation," +
+			" want func(http.ResponseWriter, *http.Request, *faas.Context) or func(http.ResponseWriter, *http.Request, *faas.Context) error" +
+			" or func(http.ResponseWriter, *http.Request, *faas.Context) (T, error)",
+		path + ":26:1: func BytesMessage: signature func(msg []byte) does not match annotation, want func(string) or func(string) error",
+		path + ":29:1: func StringEnv: signature func(env map[string]string) does not match annotation, want func(map[string]any)",
+		path + ":32:1: func StringStart: signature func(ctx string) error does not match annotation, want func(context.Context) error",
+		path + ":35:1: func NoError: signature func(ctx context.Context) bool does not match annotation, want func(context.Context) error",
+		path + ":38:1: func FuncMiddleware: signature func(next http.HandlerFunc) http.Handler does not match annotation, want func(http.Handler) http.Handler",
+	}
+	err := fixtureChecker.checkFunclets(parseFixture(t, path))
+	if err == nil {
+		t.Fatal("checkFunclets accepted bad signatures")
+	}
+	got := strings.Split(err.Error(), "\n")
+	if len(got) != len(want) {
+		t.Fatalf("got %d diagnostics, want %d:\n%v", len(got), len(want), err)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("diagnostic %d:\ngot  %s\nwant %s", i, got[i], want[i])
+		}
+	}
+}
+
+func TestCheckFuncletsTypeError(t *testing.T) {
+	path := filepath.Join("testdata", "typecheck", "broken", "funclets.go")
+	err := fixtureChecker.checkFunclets(parseFixture(t, path))
+	if want := path + ":7:10: undefined: body"; err == nil || err.Error() != want {
+		t.Errorf("checkFunclets error = %v, want %s", err, want)
+	}
+}
+
+func TestParseFileSignatures(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		err  string
+	}{
+		{"shared type counts every name", "// @onMessageFunclet msg(queue, /orders)\nfunc F(a, b string) {}", "bad function param"},
+		{"auth with two params", "// @onAuthFunclet api()\nfunc F(w http.ResponseWriter, r *http.Request) {}", "bad function param"},
+		{"auth with shared request type", "// @onAuthFunclet api()\nfunc F(w http.ResponseWriter, r, x *http.Request) {}", ""},
+		{"handler with four params", "// @onHandleFunclet api(path, /f)\nfunc F(w http.ResponseWriter, r, x, y *http.Request) {}", "bad function param"},
+		{"timing with shared type", "// @onTimingFunclet time(repeat, 5s)\nfunc F(a, b map[string]any) {}", "func param err"},
+		{"start without result", "// @onStartFunclet\nfunc F(ctx context.Context) {}", "bad function param, want func(ctx context.Context) error"},
+		{"message with result", "// @onMessageFunclet msg(queue, /orders)\nfunc F(msg string) (int, error) { return 0, nil }", "bad function result, only support error"},
+		{"gatt with result", "// @onGattFunclet fn(/f)\nfunc F(w http.ResponseWriter, r *http.Request, c *faas.Context) error { return nil }", "bad function result, onGattFunclet can not return values"},
+		{"auth bad second result", "// @onAuthFunclet api()\nfunc F(w http.ResponseWriter, r *http.Request, c *faas.Context) (int, bool) { return 0, false }", "bad function result, only support error or (T, error)"},
+		{"method", "type T struct{}\n\n// @onHandleFunclet api(path, /f)\nfunc (T) F(w http.ResponseWriter, r *http.Request) {}", "funclet must be a function, not a method"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "funclets.go")
+			if err := os.WriteFile(path, []byte("package server\n\n"+tt.src+"\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := parseFile(path, "example")
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("parseFile: %v", err)
+				}
+				return
+			}
+			// 错误带有函数声明的file:line:col
+			line := strings.Count(tt.src, "\n") + 3
+			if want := fmt.Sprintf("%s:%d:1: func F: %s", path, line, tt.err); err == nil || err.Error() != want {
+				t.Errorf("parseFile error = %v, want %s", err, want)
+			}
+		})
+	}
+}
+
+func TestCheckPrincipals(t *testing.T) {
+	path := filepath.Join("testdata", "principal", "funclets.go")
+	want := strings.Join([]string{
+		path + ":37:22: func Value: faas.Principal[User] does not match auth principal *User",
+		path + ":46:7: func AdminOnly: faas.Principal[*User] does not match auth principal *Admin",
+		path + ":52:7: func Public: faas.Principal[*User] is never set, route has no auth funclet",
+	}, "\n")
+	err := fixtureChecker.checkFunclets(parseFixture(t, path))
+	if err == nil || err.Error() != want {
+		t.Errorf("checkFunclets error:\n%v\nwant:\n%s", err, want)
+	}
+}
@@ -18,8 +18,9 @@ type Context struct {
 	//内部访问gogs/gitea的路径前缀
 	GitUrl string
 	/*******************以下内容全局变量FAAS上不存在**************************/
-	w ResponseWriter
-	r *http.Request
+	app *App
+	w   ResponseWriter
+	r   *http.Request
 	//原始请求路径
 	oriPath string
 	//沙箱注解开始的路径
@@ -30,8 +31,19 @@ type Context struct {
 	Entry string
 	//gatt 函数名
 	Fn string
-	//auth 鉴权过后设置的内容
+	//请求ID,沿用网关传入的X-Request-Id,没有时生成
+	RequestID string
+	//auth 鉴权过后设置的内容,新代码使用SetPrincipal/Principal
 	Ctx any
+
+	//匹配到的路由模式,用于指标
+	route string
+	//请求的server span
+	span *Span
+
+	principal any
+	decision  authDecision
+	denyErr   error
 }
 
 func newContext(w http.ResponseWriter, r *http.Request) *Context {
@@ -59,4 +71,12 @@ func newContext(w http.ResponseWriter, r *http.Request) *Context {
 	return c
 }
 
+// Param 返回路径参数的值,如注解路径为/users/{id}时c.Param("id")
+func (c *Context) Param(name string) string {
+	if c.r == nil {
+		return ""
+	}
+	return c.r.PathValue(name)
+}
+
 var FAAS = newContext(nil, nil)
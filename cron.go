@@ -0,0 +1,183 @@
+package faas
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron 是解析后的cron表达式,支持5段(分 时 日 月 周)和6段(秒 分 时 日 月 周)
+type Cron struct {
+	second, minute, hour, dom, month, dow uint64
+	// 日和周都不以*开头时,两者满足其一即可,与标准cron一致
+	domStar, dowStar bool
+	// 秒、分、时有以*开头的段,夏令时结束时重复的时段内照常触发,否则同一时刻只触发一次
+	wild bool
+}
+
+type cronField struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	secondField = cronField{0, 59, nil}
+	minuteField = cronField{0, 59, nil}
+	hourField   = cronField{0, 23, nil}
+	domField    = cronField{1, 31, nil}
+	monthField  = cronField{1, 12, map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// 周日可以写为0或7
+	dowField = cronField{0, 7, map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// ParseCron 解析cron表达式,每段支持 * 、数字、a-b 、/n 步长以及逗号分隔的列表,月和周支持英文缩写
+func ParseCron(expr string) (*Cron, error) {
+	fields := strings.Fields(expr)
+	if len(fields) == 5 {
+		fields = append([]string{"0"}, fields...)
+	} else if len(fields) != 6 {
+		return nil, fmt.Errorf("cron expression %q: expected 5 or 6 fields, got %d", expr, len(fields))
+	}
+	// 与Vixie cron相同,以*开头(如*/2)即视为通配
+	star := func(f string) bool {
+		return strings.HasPrefix(f, "*") || strings.HasPrefix(f, "?")
+	}
+	c := &Cron{
+		domStar: star(fields[3]),
+		dowStar: star(fields[5]),
+		wild:    star(fields[0]) || star(fields[1]) || star(fields[2]),
+	}
+	var err error
+	for i, f := range []struct {
+		bits  *uint64
+		field cronField
+	}{
+		{&c.second, secondField},
+		{&c.minute, minuteField},
+		{&c.hour, hourField},
+		{&c.dom, domField},
+		{&c.month, monthField},
+		{&c.dow, dowField},
+	} {
+		if *f.bits, err = f.field.parse(fields[i]); err != nil {
+			return nil, fmt.Errorf("cron expression %q: %w", expr, err)
+		}
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	if c.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("cron expression %q never matches", expr)
+	}
+	return c, nil
+}
+
+func (f cronField) parse(s string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(s, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("bad step in %q", part)
+			}
+			rangePart, step = part[:i], n
+		}
+		lo, hi := f.min, f.max
+		if rangePart != "*" && rangePart != "?" {
+			var err error
+			bounds := strings.SplitN(rangePart, "-", 2)
+			if lo, err = f.value(bounds[0]); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = f.value(bounds[1]); err != nil {
+					return 0, err
+				}
+			} else if step > 1 {
+				// a/n 表示从a开始到最大值
+				hi = f.max
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("bad range %q", rangePart)
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func (f cronField) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("bad value %q", s)
+	}
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("value %d out of range [%d,%d]", v, f.min, f.max)
+	}
+	return v, nil
+}
+
+func (c *Cron) matchDay(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Next 返回t之后(不含t)的下一个触发时间,使用t所在的时区,五年内没有匹配时返回零值。
+// 夏令时开始时跳过的时刻不触发;夏令时结束时重复的时刻,秒、分、时都固定的表达式只触发一次
+func (c *Cron) Next(t time.Time) time.Time {
+	loc := t.Location()
+	after := wallClock(t)
+	t = t.Truncate(time.Second).Add(time.Second)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !c.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			// 按绝对时间前进到下一个整点,夏令时开始时time.Date可能返回更早的时间
+			t = t.Add(time.Hour - time.Duration(t.Minute())*time.Minute - time.Duration(t.Second())*time.Second)
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Truncate(time.Minute).Add(time.Minute)
+			continue
+		}
+		if c.second&(1<<uint(t.Second())) == 0 {
+			t = t.Add(time.Second)
+			continue
+		}
+		if !c.wild && !wallClock(t).After(after) {
+			t = t.Add(time.Second)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// wallClock 返回t在所在时区的钟点,用于比较夏令时结束时重复的时刻
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+}
@@ -0,0 +1,142 @@
+package faas
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		expr string
+		ok   bool
+	}{
+		{"* * * * *", true},
+		{"*/15 * * * * *", true},
+		{"0 9-17/2 * * mon-fri", true},
+		{"0 0 1,15 jan,JUL ?", true},
+		{"5/10 * * * *", true},
+		{"0 0 * * 7", true},
+		{"* * * *", false},
+		{"* * * * * * *", false},
+		{"60 * * * *", false},
+		{"* 24 * * *", false},
+		{"* * 0 * *", false},
+		{"* * * 13 *", false},
+		{"* * * * 8", false},
+		{"* * * * foo", false},
+		{"5-1 * * * *", false},
+		{"*/0 * * * *", false},
+		{"*/x * * * *", false},
+		{"0 0 30 feb *", false},
+	}
+	for _, tt := range tests {
+		_, err := ParseCron(tt.expr)
+		if (err == nil) != tt.ok {
+			t.Errorf("ParseCron(%q) error = %v, want ok %v", tt.expr, err, tt.ok)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ktm, err := time.LoadLocation("Asia/Kathmandu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	date := func(loc *time.Location, s string) time.Time {
+		t.Helper()
+		d, err := time.ParseInLocation("2006-01-02 15:04:05", s, loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	tests := []struct {
+		name string
+		expr string
+		loc  *time.Location
+		from string
+		want []string
+	}{
+		{"every minute", "* * * * *", time.UTC, "2024-01-01 10:00:30",
+			[]string{"2024-01-01 10:01:00", "2024-01-01 10:02:00"}},
+		{"seconds step", "*/20 * * * * *", time.UTC, "2024-01-01 10:00:00",
+			[]string{"2024-01-01 10:00:20", "2024-01-01 10:00:40", "2024-01-01 10:01:00"}},
+		{"start with step", "5/20 * * * *", time.UTC, "2024-01-01 10:00:00",
+			[]string{"2024-01-01 10:05:00", "2024-01-01 10:25:00", "2024-01-01 10:45:00", "2024-01-01 11:05:00"}},
+		{"range with step", "0 9-17/4 * * *", time.UTC, "2024-01-01 10:00:00",
+			[]string{"2024-01-01 13:00:00", "2024-01-01 17:00:00", "2024-01-02 09:00:00"}},
+		{"list", "0 0 1,15 * *", time.UTC, "2024-01-01 00:00:00",
+			[]string{"2024-01-15 00:00:00", "2024-02-01 00:00:00"}},
+		{"names", "30 8 * jan,feb mon", time.UTC, "2024-02-20 00:00:00",
+			[]string{"2024-02-26 08:30:00", "2025-01-06 08:30:00"}},
+		{"sunday as 7", "0 0 * * 7", time.UTC, "2024-01-01 00:00:00",
+			[]string{"2024-01-07 00:00:00", "2024-01-14 00:00:00"}},
+		// 日和周都受限时满足其一即可
+		{"dom or dow", "0 0 13 * fri", time.UTC, "2024-09-01 00:00:00",
+			[]string{"2024-09-06 00:00:00", "2024-09-13 00:00:00", "2024-09-20 00:00:00", "2024-09-27 00:00:00", "2024-10-04 00:00:00", "2024-10-11 00:00:00", "2024-10-13 00:00:00"}},
+		// 以*开头的日视为通配,只按周匹配
+		{"stepped dom wildcard", "0 0 */2 * mon", time.UTC, "2024-01-01 00:00:00",
+			[]string{"2024-01-15 00:00:00", "2024-01-29 00:00:00", "2024-02-05 00:00:00"}},
+		{"stepped dow wildcard", "0 0 1 * */3", time.UTC, "2024-01-01 00:00:00",
+			[]string{"2024-05-01 00:00:00", "2024-06-01 00:00:00", "2024-09-01 00:00:00"}},
+		{"month rollover", "0 0 31 * *", time.UTC, "2024-01-31 00:00:00",
+			[]string{"2024-03-31 00:00:00", "2024-05-31 00:00:00"}},
+		{"year rollover", "0 0 1 1 *", time.UTC, "2024-06-01 00:00:00",
+			[]string{"2025-01-01 00:00:00", "2026-01-01 00:00:00"}},
+		{"leap day", "0 0 29 2 *", time.UTC, "2024-03-01 00:00:00",
+			[]string{"2028-02-29 00:00:00"}},
+		{"non-hour offset", "0 9 * * *", ktm, "2024-01-01 08:10:00",
+			[]string{"2024-01-01 09:00:00", "2024-01-02 09:00:00"}},
+		// 夏令时开始时跳过的2:30不触发
+		{"dst spring forward", "30 2 * * *", ny, "2024-03-09 12:00:00",
+			[]string{"2024-03-11 02:30:00", "2024-03-12 02:30:00"}},
+		{"dst spring forward hourly", "0 * * * *", ny, "2024-03-10 00:30:00",
+			[]string{"2024-03-10 01:00:00", "2024-03-10 03:00:00", "2024-03-10 04:00:00"}},
+		// 夏令时结束时重复的1:30只触发一次
+		{"dst fall back", "30 1 * * *", ny, "2024-11-02 12:00:00",
+			[]string{"2024-11-03 01:30:00", "2024-11-04 01:30:00"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			next := date(tt.loc, tt.from)
+			for _, want := range tt.want {
+				next = c.Next(next)
+				if got := next.Format("2006-01-02 15:04:05"); got != want {
+					t.Fatalf("Next = %s, want %s", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCronNextFallBackWildcard(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := ParseCron("*/30 * * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 通配的表达式在重复的时段内照常触发
+	next := time.Date(2024, 11, 3, 0, 45, 0, 0, ny)
+	var got []string
+	for i := 0; i < 5; i++ {
+		next = c.Next(next)
+		got = append(got, next.Format("15:04 MST"))
+	}
+	want := []string{"01:00 EDT", "01:30 EDT", "01:00 EST", "01:30 EST", "02:00 EST"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Next = %v, want %v", got, want)
+		}
+	}
+}
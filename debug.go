@@ -0,0 +1,234 @@
+package faas
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// RouteTable 已注册的入口、路由、gatt函数和定时函数,用于排查请求404等问题
+type RouteTable struct {
+	Entries []EntryInfo  `json:"entries"`
+	GattFns []string     `json:"gatt_fns,omitempty"`
+	Timings []TimingInfo `json:"timings,omitempty"`
+}
+
+// EntryInfo 入口的鉴权链和路由
+type EntryInfo struct {
+	Name string `json:"name"`
+	//入口鉴权链中鉴权函数的数量
+	Auths  int         `json:"auths"`
+	Routes []RouteInfo `json:"routes"`
+}
+
+// RouteInfo 一条路由的注册信息
+type RouteInfo struct {
+	//path、prefix,msg入口为消息类型
+	Type    string   `json:"type"`
+	Path    string   `json:"path"`
+	Methods []string `json:"methods,omitempty"`
+	//entry使用入口的鉴权链,route使用路由指定的鉴权链,none不鉴权
+	Auth         string   `json:"auth"`
+	Auths        int      `json:"auths"`
+	Timeout      string   `json:"timeout,omitempty"`
+	MaxBodyBytes int64    `json:"max_body_bytes,omitempty"`
+	ContentTypes []string `json:"content_types,omitempty"`
+	//处理函数,只有faasgen能从注解中得到
+	Handler string `json:"handler,omitempty"`
+}
+
+// TimingInfo 定时函数的调度信息
+type TimingInfo struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Interval string `json:"interval"`
+	Policy   string `json:"policy,omitempty"`
+	TimeZone string `json:"time_zone,omitempty"`
+	//下一次触发的时间,服务未运行或once类型时为空
+	Next *time.Time `json:"next,omitempty"`
+}
+
+// Routes 返回App注册的路由表
+func (a *App) Routes() RouteTable {
+	var table RouteTable
+	for _, name := range sortedKeys(a.entryMap) {
+		e := a.entryMap[name]
+		info := EntryInfo{Name: name, Auths: len(e.auths), Routes: []RouteInfo{}}
+		for _, rt := range e.routes {
+			info.Routes = append(info.Routes, rt.info()...)
+		}
+		table.Entries = append(table.Entries, info)
+	}
+	table.GattFns = sortedKeys(a.gattHandlerMap)
+	for _, t := range a.timings {
+		info := TimingInfo{Name: t.name, Type: t.timingType, Interval: t.interval, Policy: string(t.policy)}
+		if t.loc != time.Local {
+			info.TimeZone = t.loc.String()
+		}
+		t.mu.Lock()
+		if !t.next.IsZero() {
+			next := t.next
+			info.Next = &next
+		}
+		t.mu.Unlock()
+		table.Timings = append(table.Timings, info)
+	}
+	table.Sort()
+	return table
+}
+
+// info 返回route上每个handler的注册信息,同一handler的多个方法合并为一条
+func (rt *route) info() []RouteInfo {
+	methods := make(map[*routeHandler][]string)
+	var handlers []*routeHandler
+	for m, h := range rt.handlers {
+		if _, ok := methods[h]; !ok {
+			handlers = append(handlers, h)
+		}
+		if m != "" {
+			methods[h] = append(methods[h], m)
+		} else if methods[h] == nil {
+			methods[h] = []string{}
+		}
+	}
+	list := make([]RouteInfo, 0, len(handlers))
+	for _, h := range handlers {
+		info := RouteInfo{
+			Type:         h.kind,
+			Path:         h.path,
+			Methods:      methods[h],
+			Auth:         "entry",
+			Auths:        len(rt.entry.auths),
+			MaxBodyBytes: h.maxBodyBytes,
+			ContentTypes: h.contentTypes,
+		}
+		sort.Strings(info.Methods)
+		if h.overrideAuth {
+			info.Auth, info.Auths = "route", len(h.auths)
+		}
+		if info.Auths == 0 {
+			info.Auth = "none"
+		}
+		if h.timeout > 0 {
+			info.Timeout = h.timeout.String()
+		} else if h.timeout < 0 {
+			info.Timeout = "none"
+		}
+		list = append(list, info)
+	}
+	return list
+}
+
+// Sort 入口按名称排序,路由按路径、类型和方法排序,gatt函数按名称排序,保证输出稳定,
+// faasgen routes生成的路由表使用相同的顺序
+func (t *RouteTable) Sort() {
+	sort.Slice(t.Entries, func(i, j int) bool {
+		return t.Entries[i].Name < t.Entries[j].Name
+	})
+	for _, e := range t.Entries {
+		sort.SliceStable(e.Routes, func(i, j int) bool {
+			a, b := e.Routes[i], e.Routes[j]
+			if a.Path != b.Path {
+				return a.Path < b.Path
+			}
+			if a.Type != b.Type {
+				return a.Type < b.Type
+			}
+			return strings.Join(a.Methods, "|") < strings.Join(b.Methods, "|")
+		})
+	}
+	sort.Strings(t.GattFns)
+}
+
+// WriteText 以表格形式输出路由表,faasgen routes使用相同的格式
+func (t RouteTable) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	handlers := false
+	for _, e := range t.Entries {
+		for _, r := range e.Routes {
+			handlers = handlers || r.Handler != ""
+		}
+	}
+	header := "ENTRY\tTYPE\tPATH\tMETHODS\tAUTH\tOPTIONS"
+	if handlers {
+		header += "\tHANDLER"
+	}
+	fmt.Fprintln(tw, header)
+	for _, e := range t.Entries {
+		for _, r := range e.Routes {
+			methods := "*"
+			if len(r.Methods) != 0 {
+				methods = strings.Join(r.Methods, "|")
+			}
+			auth := r.Auth
+			if auth != "none" {
+				auth += "(" + strconv.Itoa(r.Auths) + ")"
+			}
+			line := e.Name + "\t" + r.Type + "\t" + r.Path + "\t" + methods + "\t" + auth + "\t" + r.options()
+			if handlers {
+				line += "\t" + r.Handler
+			}
+			fmt.Fprintln(tw, line)
+		}
+	}
+	if len(t.GattFns) != 0 {
+		fmt.Fprintln(tw, "\nGATT FN")
+		for _, fn := range t.GattFns {
+			fmt.Fprintln(tw, fn)
+		}
+	}
+	if len(t.Timings) != 0 {
+		fmt.Fprintln(tw, "\nTIMING\tTYPE\tINTERVAL\tPOLICY\tTIME ZONE")
+		for _, tm := range t.Timings {
+			fmt.Fprintln(tw, tm.Name+"\t"+tm.Type+"\t"+orDash(tm.Interval)+"\t"+orDash(tm.Policy)+"\t"+orDash(tm.TimeZone))
+		}
+	}
+	return tw.Flush()
+}
+
+// options 返回路由的可选配置,如timeout=5s maxbody=1048576
+func (r RouteInfo) options() string {
+	var opts []string
+	if r.Timeout != "" {
+		opts = append(opts, "timeout="+r.Timeout)
+	}
+	if r.MaxBodyBytes < 0 {
+		opts = append(opts, "maxbody=none")
+	} else if r.MaxBodyBytes > 0 {
+		opts = append(opts, "maxbody="+strconv.FormatInt(r.MaxBodyBytes, 10))
+	}
+	if len(r.ContentTypes) != 0 {
+		opts = append(opts, "consumes="+strings.Join(r.ContentTypes, ","))
+	}
+	return orDash(strings.Join(opts, " "))
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// DebugHandler 返回输出路由表的handler,默认为JSON,format=text时为表格,
+// 设置了DebugPath时由ServeHTTP提供
+func (a *App) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		table := a.Routes()
+		if r.URL.Query().Get("format") == "text" {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			table.WriteText(w)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(table)
+	})
+}
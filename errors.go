@@ -0,0 +1,146 @@
+package faas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// HTTPError 携带HTTP状态码的错误,handler返回它(或包装了它的错误)时按Status响应
+type HTTPError struct {
+	Status int
+	//返回给客户端的内容,为空时使用状态码的标准文本
+	Message string
+	Err     error
+}
+
+func (e *HTTPError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.Status)
+	}
+	if e.Err != nil {
+		return msg + ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// Is 状态码相同的HTTPError视为同一错误,使errors.Is(err, ErrNotFound)可用
+func (e *HTTPError) Is(target error) bool {
+	t, ok := target.(*HTTPError)
+	return ok && t.Status == e.Status && t.Message == "" && t.Err == nil
+}
+
+// NewHTTPError 创建指定状态码和返回内容的错误
+func NewHTTPError(status int, format string, args ...any) *HTTPError {
+	return &HTTPError{Status: status, Message: fmt.Sprintf(format, args...)}
+}
+
+// 常用状态码对应的错误,可用fmt.Errorf("...: %w", faas.ErrNotFound)包装后返回
+var (
+	ErrBadRequest         = &HTTPError{Status: http.StatusBadRequest}
+	ErrUnauthorized       = &HTTPError{Status: http.StatusUnauthorized}
+	ErrForbidden          = &HTTPError{Status: http.StatusForbidden}
+	ErrNotFound           = &HTTPError{Status: http.StatusNotFound}
+	ErrConflict           = &HTTPError{Status: http.StatusConflict}
+	ErrTooManyRequests    = &HTTPError{Status: http.StatusTooManyRequests}
+	ErrServiceUnavailable = &HTTPError{Status: http.StatusServiceUnavailable}
+)
+
+// StatusCode 返回错误对应的HTTP状态码,不是HTTPError时,
+// context.DeadlineExceeded为504,context.Canceled为503,其他为500
+func StatusCode(err error) int {
+	var he *HTTPError
+	if errors.As(err, &he) {
+		return he.Status
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
+	if errors.Is(err, context.Canceled) {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// WriteError 按错误的状态码写响应,5xx错误上报给ErrorReporter且不向客户端暴露错误内容,
+// handler已经写了响应时只上报或记录错误,不再写响应
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	status, msg := resolveError(r, err)
+	if responseStarted(w, r, status, err) {
+		return
+	}
+	http.Error(w, msg, status)
+}
+
+// responseStarted 返回w是否已经写了状态码,此时无法再改写状态码,追加错误内容会混入已写的响应体。
+// 5xx错误已由resolveError上报,其他错误记录到日志
+func responseStarted(w http.ResponseWriter, r *http.Request, status int, err error) bool {
+	rw, ok := w.(ResponseWriter)
+	if !ok || rw.Status() == 0 {
+		return false
+	}
+	if c, ok := r.Context().Value(contextKey).(*Context); ok && status < http.StatusInternalServerError {
+		c.Logger().Warn("handler returned an error after writing the response",
+			"status", rw.Status(), "error_status", status, "error", err)
+	}
+	return true
+}
+
+// resolveError 返回错误对应的状态码和响应内容,5xx错误上报给ErrorReporter,
+// 请求的context已结束时不上报,超时已由timeoutHandler上报,客户端断开不是服务端的错误
+func resolveError(r *http.Request, err error) (int, string) {
+	status := StatusCode(err)
+	msg := http.StatusText(status)
+	var he *HTTPError
+	if errors.As(err, &he) && he.Message != "" {
+		msg = he.Message
+	}
+	if status >= http.StatusInternalServerError && r.Context().Err() == nil {
+		if c, ok := r.Context().Value(contextKey).(*Context); ok && c.app != nil {
+			kind, name := c.funclet()
+			c.app.report(&FuncletError{Kind: kind, Name: name, Err: err, Request: r})
+		}
+	}
+	return status, msg
+}
+
+// ErrorHandler 将返回错误的handler适配为http.HandlerFunc
+func ErrorHandler(handler func(http.ResponseWriter, *http.Request) error) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := handler(w, r); err != nil {
+			WriteError(w, r, err)
+		}
+	})
+}
+
+// WithContextErrorHandler 将带Context且返回错误的handler适配为http.HandlerFunc
+func WithContextErrorHandler(handler func(http.ResponseWriter, *http.Request, *Context) error) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, _ := r.Context().Value(contextKey).(*Context)
+		if err := handler(w, r, c); err != nil {
+			WriteError(w, r, err)
+		}
+	})
+}
+
+// MessageErrorHandler 与MessageHandler相同,handler返回错误时按错误的状态码响应
+func MessageErrorHandler(handler func(string) error) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := readBody(r)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+		if err := handler(string(body)); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+}
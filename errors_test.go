@@ -0,0 +1,121 @@
+package faas
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStatusCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"sentinel", ErrNotFound, http.StatusNotFound},
+		{"wrapped sentinel", fmt.Errorf("load user 1: %w", ErrNotFound), http.StatusNotFound},
+		{"twice wrapped sentinel", fmt.Errorf("handler: %w", fmt.Errorf("store: %w", ErrConflict)), http.StatusConflict},
+		{"joined", errors.Join(errors.New("audit"), ErrForbidden), http.StatusForbidden},
+		{"custom", NewHTTPError(http.StatusTeapot, "short and stout"), http.StatusTeapot},
+		{"http error wins over context", &HTTPError{Status: http.StatusBadRequest, Err: context.DeadlineExceeded}, http.StatusBadRequest},
+		{"deadline", fmt.Errorf("query: %w", context.DeadlineExceeded), http.StatusGatewayTimeout},
+		{"canceled", context.Canceled, http.StatusServiceUnavailable},
+		{"plain", errors.New("db down"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		if got := StatusCode(tt.err); got != tt.want {
+			t.Errorf("%s: StatusCode(%v) = %d, want %d", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestHTTPErrorIs(t *testing.T) {
+	if !errors.Is(NewHTTPError(http.StatusNotFound, "no user %d", 1), ErrNotFound) {
+		t.Error("404 with a message is not ErrNotFound")
+	}
+	if !errors.Is(fmt.Errorf("wrap: %w", &HTTPError{Status: http.StatusNotFound, Err: errors.New("x")}), ErrNotFound) {
+		t.Error("wrapped 404 is not ErrNotFound")
+	}
+	if errors.Is(ErrNotFound, NewHTTPError(http.StatusNotFound, "no user")) {
+		t.Error("ErrNotFound matches a target with a message")
+	}
+	if errors.Is(ErrNotFound, ErrConflict) {
+		t.Error("ErrNotFound is ErrConflict")
+	}
+}
+
+func TestErrorHandler(t *testing.T) {
+	var logs bytes.Buffer
+	var reported []*FuncletError
+	app := New()
+	app.Logger = slog.New(slog.NewTextHandler(&logs, nil))
+	app.ErrorReporter = ErrorReporterFunc(func(err *FuncletError) {
+		reported = append(reported, err)
+	})
+	handle := func(path string, err error, write func(w http.ResponseWriter)) {
+		app.HandleFunc("api", "path", path, ErrorHandler(func(w http.ResponseWriter, r *http.Request) error {
+			if write != nil {
+				write(w)
+			}
+			return err
+		}))
+	}
+	handle("/ok", nil, func(w http.ResponseWriter) { w.Write([]byte("ok")) })
+	handle("/notfound", fmt.Errorf("user 1: %w", ErrNotFound), nil)
+	handle("/conflict", NewHTTPError(http.StatusConflict, "name taken"), nil)
+	handle("/internal", errors.New("db password is hunter2"), nil)
+	handle("/partial", errors.New("connection reset"), func(w http.ResponseWriter) { w.Write([]byte("partial")) })
+	handle("/created", ErrBadRequest, func(w http.ResponseWriter) { w.WriteHeader(http.StatusCreated) })
+
+	tests := []struct {
+		path     string
+		status   int
+		body     string
+		reported bool
+	}{
+		{"/ok", http.StatusOK, "ok", false},
+		{"/notfound", http.StatusNotFound, "Not Found\n", false},
+		{"/conflict", http.StatusConflict, "name taken\n", false},
+		// 5xx不向客户端暴露错误内容
+		{"/internal", http.StatusInternalServerError, "Internal Server Error\n", true},
+		// 已经写了响应时只上报,不追加错误内容
+		{"/partial", http.StatusOK, "partial", true},
+		{"/created", http.StatusCreated, "", false},
+	}
+	for _, tt := range tests {
+		reported = nil
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.status || w.Body.String() != tt.body {
+			t.Errorf("%s: got %d %q, want %d %q", tt.path, w.Code, w.Body.String(), tt.status, tt.body)
+		}
+		if got := len(reported) == 1; got != tt.reported || len(reported) > 1 {
+			t.Errorf("%s: reported %v, want reported %v", tt.path, reported, tt.reported)
+		}
+	}
+	if !strings.Contains(logs.String(), "handler returned an error after writing the response") {
+		t.Errorf("4xx error after the response was written is not logged:\n%s", logs.String())
+	}
+}
+
+func TestMessageErrorHandler(t *testing.T) {
+	h := MessageErrorHandler(func(msg string) error {
+		if msg == "bad" {
+			return fmt.Errorf("parse: %w", ErrBadRequest)
+		}
+		return nil
+	})
+	for body, want := range map[string]int{"good": http.StatusOK, "bad": http.StatusBadRequest} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+		if w.Code != want {
+			t.Errorf("message %q: status = %d, want %d", body, w.Code, want)
+		}
+	}
+}
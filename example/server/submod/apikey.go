@@ -0,0 +1,26 @@
+package submod
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/faasteam/faas"
+	"github.com/faasteam/faas/auth"
+)
+
+// DataDir下的apikeys.txt,每行为"key 名称"
+var apiKeyAuth = auth.APIKey(auth.APIKeyConfig{KeysFile: "apikeys.txt"})
+
+// 使用内置的API Key鉴权,只在@auth apikey的路由上执行
+// @onAuthFunclet api(apikey)
+func APIKeyAuth(w http.ResponseWriter, r *http.Request, c *faas.Context) error {
+	return apiKeyAuth(w, r, c)
+}
+
+// 只接受API Key调用
+// @auth apikey
+// @onHandleFunclet api(path,/report)
+func Report(w http.ResponseWriter, r *http.Request, c *faas.Context) {
+	claims, _ := auth.FromContext(c)
+	fmt.Fprintf(w, "report for %s", claims.Subject())
+}
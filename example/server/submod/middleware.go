@@ -0,0 +1,35 @@
+package submod
+
+import (
+	"log"
+	"net/http"
+	"time"
+)
+
+// 入口api下所有请求的耗时日志
+// @onMiddleware api()
+func Logging(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		next.ServeHTTP(w, r)
+		log.Printf("Logging %s %s %v", r.Method, r.URL.Path, time.Since(start))
+	})
+}
+
+// /users下的请求允许跨域
+// @onMiddleware api(prefix,/users) order(1)
+func CORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// 只作用于/users/{id}这一个路由
+// @onMiddleware api(path,/users/{id})
+func NoCache(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		next.ServeHTTP(w, r)
+	})
+}
@@ -2,8 +2,8 @@ package submod
 
 import (
 	"fmt"
-	"log"
 	"net/http"
+	"time"
 
 	"github.com/faasteam/faas"
 )
@@ -20,6 +20,17 @@ func ContextPrefixHandler(w http.ResponseWriter, r *http.Request, c *faas.Contex
 	fmt.Fprintf(w, "ContextPrefixHandler Request received for path: %s", r.URL.Path)
 }
 
+// 返回错误的handler,错误按faas.HTTPError的状态码响应
+// @onHandleFunclet api(path,/c)
+func ContextErrorHandler(w http.ResponseWriter, r *http.Request, c *faas.Context) error {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		return fmt.Errorf("query id: %w", faas.ErrBadRequest)
+	}
+	fmt.Fprintf(w, "ContextErrorHandler Request received for id: %s", id)
+	return nil
+}
+
 // 注解gatt的路径和资源路径
 // @onGattEntry  api(/gatt,res)
 func GattEntry(w http.ResponseWriter, r *http.Request, c *faas.Context) {
@@ -52,8 +63,60 @@ func StaticHandler(w http.ResponseWriter, r *http.Request, c *faas.Context) {
 	fmt.Fprintf(w, "StaticHandler Request received for path: %s not found", r.URL.Path)
 }
 
-// 希望入口为api的都经过此函数做鉴权
-// @onAuthFunclet api()
+// 希望入口为api的都经过此函数做鉴权,同一入口有多个鉴权函数时按order从小到大执行
+// @onAuthFunclet api() order(1)
 func AuthHandler(w http.ResponseWriter, r *http.Request, c *faas.Context) {
-	log.Printf("AuthHandler Request received for path: %s", r.URL.Path)
+	c.Logger().Debug("auth", "user", r.Header.Get("X-User"))
+	w.Header().Set("X-Auth", "checked")
+	c.SetPrincipal(r.Header.Get("X-User"))
+}
+
+// 入口为api的第二个鉴权函数,在AuthHandler之后执行,调用Deny拒绝请求
+// @onAuthFunclet api() order(2)
+func BanHandler(w http.ResponseWriter, r *http.Request, c *faas.Context) {
+	if r.Header.Get("X-Banned") != "" {
+		c.Deny(http.StatusForbidden, "banned")
+	}
+}
+
+// 具名鉴权函数,不加入入口的默认鉴权链,只在@auth admin的路由上代替默认鉴权链执行
+// @onAuthFunclet api(admin)
+func AdminAuth(w http.ResponseWriter, r *http.Request, c *faas.Context) error {
+	if r.Header.Get("X-Admin") == "" {
+		return faas.ErrUnauthorized
+	}
+	c.SetPrincipal("admin")
+	return nil
+}
+
+// 健康检查不需要鉴权
+// @auth none
+// @onHandleFunclet api(path,/ping)
+func Ping(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("pong"))
+}
+
+// 只有管理员可以访问
+// @auth admin
+// @onHandleFunclet api(path,/admin)
+func Admin(w http.ResponseWriter, r *http.Request, c *faas.Context) {
+	fmt.Fprintf(w, "hello %v", c.Principal())
+}
+
+// 带路径参数的前缀模式,/posts/1/a/b 的SubPath为/a/b
+// @onHandleFunclet api(prefix,/posts/{id})
+func PostFiles(w http.ResponseWriter, r *http.Request, c *faas.Context) {
+	fmt.Fprintf(w, "PostFiles id: %s subPath: %s", c.Param("id"), c.SubPath)
+}
+
+// 处理时长超过2s时r.Context()被取消并响应504
+// @onHandleFunclet api(path,/slow,GET) timeout(2s)
+func Slow(w http.ResponseWriter, r *http.Request) error {
+	select {
+	case <-time.After(5 * time.Second):
+		w.Write([]byte("done"))
+		return nil
+	case <-r.Context().Done():
+		return r.Context().Err()
+	}
 }
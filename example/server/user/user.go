@@ -0,0 +1,85 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/faasteam/faas"
+)
+
+type CreateUser struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+	// 来自查询参数 ?dry=true
+	DryRun bool `json:"-" query:"dry"`
+}
+
+func (u *CreateUser) Validate() error {
+	if u.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+type User struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+// 请求体按JSON解码到CreateUser,返回的User按JSON响应
+// @onHandleFunclet api(path,/users,POST) maxbody(64KB) consumes(application/json)
+func Create(c *faas.Context, req *CreateUser) (*User, error) {
+	if req.Name == "admin" {
+		return nil, fmt.Errorf("user %s: %w", req.Name, faas.ErrConflict)
+	}
+	return &User{ID: 1, Name: req.Name, Age: req.Age}, nil
+}
+
+type ListUsers struct {
+	Limit int `query:"limit"`
+}
+
+// 同一路径按方法分发到不同的funclet
+// @onHandleFunclet api(path,/users,GET)
+func List(c *faas.Context, req *ListUsers) (*[]User, error) {
+	users := []User{{ID: 1, Name: "bob"}}
+	return &users, nil
+}
+
+type GetUser struct {
+	ID int `path:"id"`
+}
+
+// 路径参数,/users/1 的id为1
+// @onHandleFunclet api(path,/users/{id},GET)
+func Get(c *faas.Context, req *GetUser) (*User, error) {
+	if req.ID != 1 {
+		return nil, fmt.Errorf("user %s: %w", c.Param("id"), faas.ErrNotFound)
+	}
+	return &User{ID: req.ID, Name: "bob"}, nil
+}
+
+// Session 鉴权后的调用者身份
+type Session struct {
+	UserID string
+}
+
+// 返回(T, error)的鉴权函数,faasgen检查使用它的路由中faas.Principal[T]的T与之一致
+// @onAuthFunclet api(session)
+func SessionAuth(w http.ResponseWriter, r *http.Request, c *faas.Context) (*Session, error) {
+	id := r.Header.Get("X-User")
+	if id == "" {
+		return nil, faas.ErrUnauthorized
+	}
+	return &Session{UserID: id}, nil
+}
+
+// 当前登录的用户
+// @auth session
+// @onHandleFunclet api(path,/me,GET)
+func Me(c *faas.Context, req *struct{}) (*User, error) {
+	s, _ := faas.Principal[*Session](c)
+	return &User{ID: 1, Name: s.UserID}, nil
+}
@@ -1,9 +1,12 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
+
+	"github.com/faasteam/faas"
 )
 
 // 希望完整匹配到/a路由的函数进入此函数
@@ -18,8 +21,8 @@ func PrefixHandler(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, "PrefixHandler Request received for path: %s", r.URL.Path)
 }
 
-// 5s重复执行的注解
-// @onTimingFunclet time(repeat,5s)
+// 5s重复执行的注解,上一次未结束时跳过本次
+// @onTimingFunclet time(repeat,5s,policy=skip)
 func RepeatFunclet(env map[string]any) {
 	log.Println("RepeatFunclet in 5s......")
 }
@@ -30,6 +33,18 @@ func EveryDayFunclet(env map[string]any) {
 	log.Println("EveryDayFunclet in 13h......")
 }
 
+// 北京时间每天13点30分执行的注解
+// @onTimingFunclet time(everyday,"13:30",tz=Asia/Shanghai)
+func EveryDayTZFunclet(env map[string]any) {
+	log.Println("EveryDayTZFunclet in 13:30 Asia/Shanghai......")
+}
+
+// 工作日9点到18点每15分钟执行的注解
+// @onTimingFunclet time(cron, "*/15 9-18 * * 1-5")
+func CronFunclet(env map[string]any) {
+	log.Println("CronFunclet in......")
+}
+
 // 启动的时候执行一次的注解
 // @onTimingFunclet time(once)
 func OnceFunclet(env map[string]any) {
@@ -40,3 +55,27 @@ func OnceFunclet(env map[string]any) {
 func RecvExitMsg(msgstr string) {
 	log.Println("recv:", msgstr)
 }
+
+// 返回错误的消息处理函数,出错时不再响应ok
+// @onMessageFunclet msg(potter,start) timeout(30s) maxbody(1MB)
+func RecvStartMsg(msgstr string) error {
+	if msgstr == "" {
+		return faas.NewHTTPError(http.StatusBadRequest, "empty message")
+	}
+	log.Println("recv:", msgstr)
+	return nil
+}
+
+// 开始监听前执行,返回错误则启动失败
+// @onStartFunclet order(1)
+func OnStart(ctx context.Context) error {
+	log.Println("OnStart......")
+	return nil
+}
+
+// 退出时在请求和定时函数结束后执行
+// @onStopFunclet
+func OnStop(ctx context.Context) error {
+	log.Println("OnStop......")
+	return nil
+}
@@ -2,12 +2,15 @@ package faas
 
 import (
 	"context"
-	"io"
-	"log"
+	"fmt"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 )
 
@@ -16,44 +19,95 @@ type contextKeyType string
 const contextKey contextKeyType = "contextkey"
 
 type Entry struct {
+	app    *App
 	name   string
-	auth   func(http.ResponseWriter, *http.Request, *Context)
+	auths  []AuthFunc
 	router http.ServeMux
-}
+	routes map[string]*route
+	//有路由覆盖了鉴权链时才需要在鉴权前查找路由
+	authOverridden bool
 
-var entryMap map[string]*Entry = make(map[string]*Entry)
+	middlewares       []Middleware
+	prefixMiddlewares []prefixMiddleware
+	pathMiddlewares   map[string][]Middleware
 
-func beforeDispatch() http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		c := newContext(w, r)
-		ctx := context.WithValue(r.Context(), contextKey, c)
-		r = r.WithContext(ctx)
-		entry, ok := entryMap[c.Entry]
-		if !ok {
-			http.Error(w, "Not Found", http.StatusNotFound)
-			return
-		}
-		if entry.auth != nil {
-			entry.auth(c.w, r, c)
-			if c.w.Status() != 0 {
-				return
-			}
-		}
-		r.URL.Path = c.RelPath
-		entry.router.ServeHTTP(c.w, r)
-	})
+	maxBodyBytes int64
+}
+
+func (a *App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if a.MetricsPath != "" && r.URL.Path == a.MetricsPath {
+		a.MetricsHandler().ServeHTTP(w, r)
+		return
+	}
+	if h := a.healthHandler(r.URL.Path); h != nil {
+		h.ServeHTTP(w, r)
+		return
+	}
+	if a.DebugPath != "" && r.URL.Path == a.DebugPath {
+		a.DebugHandler().ServeHTTP(w, r)
+		return
+	}
+	if a.OpenAPIPath != "" && r.URL.Path == a.OpenAPIPath {
+		a.OpenAPIHandler().ServeHTTP(w, r)
+		return
+	}
+	start := time.Now()
+	c := newContext(w, r)
+	c.app = a
+	c.RequestID = requestID(r)
+	r.Header.Set(RequestIDHeader, c.RequestID)
+	w.Header().Set(RequestIDHeader, c.RequestID)
+	ctx, span := a.tracer.start(extractTrace(r.Context(), r.Header), "", SpanKindServer)
+	c.span = span
+	ctx = context.WithValue(ctx, contextKey, c)
+	r = r.WithContext(ctx)
+	defer a.observe(c, r, start)
+	defer a.recoverHTTP(c, r)
+	entry, ok := a.entryMap[c.Entry]
+	if !ok {
+		http.Error(c.w, "Not Found", http.StatusNotFound)
+		return
+	}
+	if !c.authorize(r, entry.routeAuths(r, c.RelPath)) {
+		return
+	}
+	r.URL.Path = c.RelPath
+	r, span = startSpan(r, "dispatch")
+	defer span.End()
+	chain(&entry.router, entry.dispatchMiddlewares(c.RelPath)).ServeHTTP(c.w, r)
 }
 
 func beforeHandle(next http.Handler, path string) http.Handler {
+	segments := -1
+	if strings.Contains(path, "{") {
+		segments = strings.Count(path, "/")
+	}
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if c, ok := r.Context().Value(contextKey).(*Context); ok {
 			r.URL.Path = c.oriPath
-			c.SubPath = c.RelPath[len(path):]
+			c.r = r
+			if segments < 0 {
+				c.SubPath = c.RelPath[len(path):]
+			} else {
+				c.SubPath = subPath(c.RelPath, segments)
+			}
 		}
 		next.ServeHTTP(w, r)
 	})
 }
 
+// subPath 去掉relPath开头的segments段路径,用于带通配符的前缀模式
+func subPath(relPath string, segments int) string {
+	for i := 0; i < segments; i++ {
+		j := strings.Index(relPath[1:], "/")
+		if j < 0 {
+			return ""
+		}
+		relPath = relPath[j+1:]
+	}
+	return relPath
+}
+
 func WithContextHandler(handler func(http.ResponseWriter, *http.Request, *Context)) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		c, _ := r.Context().Value(contextKey).(*Context)
@@ -61,100 +115,261 @@ func WithContextHandler(handler func(http.ResponseWriter, *http.Request, *Contex
 	})
 }
 
+// MessageHandler 将消息处理函数适配为http.HandlerFunc,读取消息失败时不调用handler,
+// 超出大小限制响应413,其他读取错误响应400
 func MessageHandler(handler func(string)) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r, err := io.ReadAll(r.Body); err == nil {
-			handler(string(r))
+		body, err := readBody(r)
+		if err != nil {
+			WriteError(w, r, err)
+			return
 		}
+		handler(string(body))
 		w.Write([]byte("ok"))
 	})
 }
 
-func Run() {
-	addr := os.Getenv("SU_SERVER_ADDR")
+// Run 启动服务,收到SIGINT/SIGTERM后优雅退出,出错时结束进程
+func (a *App) Run() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := a.RunContext(ctx); err != nil {
+		a.logger().Error("server stopped", "error", err)
+		os.Exit(1)
+	}
+}
+
+// RunContext 启动服务和定时函数,ctx结束后停止接收新请求并取消定时函数,
+// 在ShutdownTimeout内等待处理中的请求和定时函数完成
+func (a *App) RunContext(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for _, hook := range a.startHooks {
+		err := safeCall("start", funcName(hook), func() error {
+			return hook(ctx)
+		})
+		if err != nil {
+			return fmt.Errorf("start hook failed: %w", err)
+		}
+	}
+
+	// 先监听端口,端口被占用等错误在启动定时函数和就绪前返回
+	ln, err := net.Listen("tcp", listenAddr(a.Addr))
+	if err != nil {
+		a.stop(context.Background())
+		return err
+	}
+	var metricsLn net.Listener
+	if a.MetricsAddr != "" {
+		if metricsLn, err = net.Listen("tcp", a.MetricsAddr); err != nil {
+			ln.Close()
+			a.stop(context.Background())
+			return fmt.Errorf("metrics server: %w", err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, t := range a.timings {
+		t.start(ctx, &wg)
+	}
+
+	srv := &http.Server{
+		Addr:              a.Addr,
+		Handler:           a,
+		ReadHeaderTimeout: a.ReadHeaderTimeout,
+		IdleTimeout:       a.IdleTimeout,
+	}
+	errCh := make(chan error, 2)
+	go func() {
+		errCh <- srv.Serve(ln)
+	}()
+	if metricsLn != nil {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", a.MetricsHandler())
+		metricsSrv := &http.Server{Addr: a.MetricsAddr, Handler: mux, ReadHeaderTimeout: a.ReadHeaderTimeout}
+		go func() {
+			if err := metricsSrv.Serve(metricsLn); err != http.ErrServerClosed {
+				errCh <- fmt.Errorf("metrics server: %w", err)
+			}
+		}()
+		defer metricsSrv.Close()
+		a.logger().Info("metrics listening", "addr", metricsLn.Addr().String(), "path", "/metrics")
+	}
+	a.ready.Store(true)
+	a.logger().Info("server listening", "addr", ln.Addr().String())
+
+	// 任一服务出错时同样关闭主服务并等待处理中的请求,Run返回后不再有服务在运行
+	var serveErr error
+	select {
+	case serveErr = <-errCh:
+	case <-ctx.Done():
+	}
+
+	a.ready.Store(false)
+	a.logger().Info("server shutting down", "drain_timeout", a.ShutdownTimeout)
+	cancel()
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), a.ShutdownTimeout)
+	defer cancelShutdown()
+	err = srv.Shutdown(shutdownCtx)
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-shutdownCtx.Done():
+		if err == nil {
+			err = shutdownCtx.Err()
+		}
+	}
+	a.stop(shutdownCtx)
+	if serveErr != nil {
+		return serveErr
+	}
+	return err
+}
+
+// listenAddr 与http.Server相同,地址为空时监听:http
+func listenAddr(addr string) string {
 	if addr == "" {
-		addr = ":8080"
+		return ":http"
 	}
-	log.Println("Server listening on ", addr)
-	log.Fatal(http.ListenAndServe(addr, beforeDispatch()))
+	return addr
 }
 
-func HandleAuth(entryName string, handler func(http.ResponseWriter, *http.Request, *Context)) {
-	log.Printf("Registering auth entryName:%s\n", entryName)
-	entry, ok := entryMap[entryName]
-	if !ok {
-		entry = &Entry{name: entryName}
-		entryMap[entryName] = entry
+// OnStart 注册启动钩子,按注册顺序在定时函数和监听开始前执行,全部完成后服务才就绪,
+// 任一钩子出错则启动失败且不执行停止钩子
+func (a *App) OnStart(hook func(ctx context.Context) error) {
+	a.startHooks = append(a.startHooks, hook)
+}
+
+// OnStop 注册停止钩子,退出时在请求和定时函数结束后按注册的逆序执行
+func (a *App) OnStop(hook func(ctx context.Context) error) {
+	a.stopHooks = append(a.stopHooks, hook)
+}
+
+func (a *App) stop(ctx context.Context) {
+	for i := len(a.stopHooks) - 1; i >= 0; i-- {
+		hook := a.stopHooks[i]
+		err := safeCall("stop", funcName(hook), func() error {
+			return hook(ctx)
+		})
+		if err != nil {
+			a.reportError(err)
+		}
 	}
-	entry.auth = handler
+	a.tracer.flush(ctx)
 }
 
-func HandleFunc(entryName, handlerType, path string, handler func(http.ResponseWriter, *http.Request)) {
-	log.Printf("Registering router entryName:%s type:%s path:%s\n", entryName, handlerType, path)
-	entry, ok := entryMap[entryName]
+func (a *App) entry(entryName string) *Entry {
+	entry, ok := a.entryMap[entryName]
 	if !ok {
-		entry = &Entry{name: entryName}
-		entryMap[entryName] = entry
+		entry = &Entry{app: a, name: entryName}
+		a.entryMap[entryName] = entry
+	}
+	return entry
+}
+
+// HandleAuth 向入口的鉴权链追加鉴权函数,请求按注册顺序依次鉴权,
+// 鉴权函数调用Deny或没有调用Allow而写了响应时中止
+func (a *App) HandleAuth(entryName string, handler func(http.ResponseWriter, *http.Request, *Context)) {
+	a.HandleAuthFunc(entryName, authFunc(handler))
+}
+
+// HandleAuthFunc 与HandleAuth相同,鉴权函数返回错误时按错误的状态码拒绝请求
+func (a *App) HandleAuthFunc(entryName string, handler AuthFunc) {
+	a.logger().Info("registering auth", "entry", entryName)
+	entry := a.entry(entryName)
+	entry.auths = append(entry.auths, handler)
+}
+
+func (a *App) HandleFunc(entryName, handlerType, path string, handler func(http.ResponseWriter, *http.Request), opts ...RouteOption) {
+	var o routeOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	attrs := []any{"entry", entryName, "type", handlerType, "path", path}
+	if len(o.methods) != 0 {
+		attrs = append(attrs, "methods", strings.Join(o.methods, "|"))
+	}
+	a.logger().Info("registering route", attrs...)
+	if entryName != "msg" && a.healthHandler(path) != nil {
+		a.logger().Warn("route is shadowed by a health endpoint", "entry", entryName, "path", path)
+	}
+	entry := a.entry(entryName)
+	rh := &routeHandler{
+		kind:         handlerType,
+		path:         path,
+		overrideAuth: o.overrideAuth,
+		auths:        o.auths,
+		timeout:      o.timeout,
+		maxBodyBytes: o.maxBodyBytes,
+		contentTypes: o.contentTypes,
+	}
+	if o.overrideAuth {
+		entry.authOverridden = true
 	}
 	if entryName == "msg" {
-		entry.router.HandleFunc(filepath.Join(handlerType, path), handler)
+		rh.handler = chain(http.HandlerFunc(handler), o.middlewares)
+		entry.handle(filepath.Join(handlerType, path), o.methods, rh)
 	} else {
 		if handlerType == "path" {
-			entry.router.Handle(path, beforeHandle(http.HandlerFunc(handler), path))
+			rh.handler = chain(beforeHandle(http.HandlerFunc(handler), path), o.middlewares)
+			entry.handle(path, o.methods, rh)
 		} else if handlerType == "prefix" {
 			if !strings.HasSuffix(path, "/") {
 				path = path + "/"
 			}
-			entry.router.Handle(path, beforeHandle(http.HandlerFunc(handler), strings.TrimSuffix(path, "/")))
+			rh.path = path
+			rh.handler = chain(beforeHandle(http.HandlerFunc(handler), strings.TrimSuffix(path, "/")), o.middlewares)
+			entry.handle(path, o.methods, rh)
 		}
 	}
 }
 
-func TimingFunc(timingType, interval string, handler func(env map[string]any)) {
-	log.Printf("Registering timing type:%s interval: %s\n", timingType, interval)
-	env := make(map[string]any)
-	env["trigertype"] = timingType
-	env["interval"] = interval
-	switch timingType {
-	case "repeat":
-		go func() {
-			duration, err := time.ParseDuration(interval)
-			if err != nil {
-				log.Printf("Error parsing duration for repeat timing function: %v\n", err)
-				return
-			}
-			timer := time.NewTicker(duration)
-			handler(env)
-			for range timer.C {
-				handler(env)
-			}
-		}()
-	case "everyday":
-		go func() {
-			duration, err := time.ParseDuration("11h53m3s")
-			if err != nil {
-				log.Printf("Error parsing duration for everyday timing function: %v\n", err)
-				return
-			}
-			for {
-				now := time.Now()
-				sec := int(duration / time.Second)
-				h := sec / 3600
-				m := sec / 60 % 60
-				s := sec % 60
-				targetTime := time.Date(now.Year(), now.Month(), now.Day(), h, m, s, 0, now.Location())
-				if now.After(targetTime) {
-					targetTime = targetTime.Add(24 * time.Hour)
-				}
-				sleepDuration := targetTime.Sub(now)
-				log.Printf("Next execution for everyday timing function at: %v (sleeping for %v)\n", targetTime, sleepDuration)
-				time.Sleep(sleepDuration)
-				handler(env)
-			}
-		}()
-	case "once":
-		go func() {
-			handler(env)
-		}()
+// handle 将handler按方法注册到pattern对应的route上,同一pattern只向ServeMux注册一次
+func (e *Entry) handle(pattern string, methods []string, handler *routeHandler) {
+	if e.routes == nil {
+		e.routes = make(map[string]*route)
 	}
+	rt, ok := e.routes[pattern]
+	if !ok {
+		rt = &route{entry: e, pattern: pattern, handlers: make(map[string]*routeHandler)}
+		e.routes[pattern] = rt
+		e.router.Handle(pattern, rt)
+	}
+	rt.add(methods, handler)
+}
+
+// routeAuths 返回请求使用的鉴权链,路由通过NoAuth或WithAuth覆盖时使用路由的配置。
+// 路由自动应答的OPTIONS不鉴权,CORS预检请求不带凭据
+func (e *Entry) routeAuths(r *http.Request, relPath string) []AuthFunc {
+	if !e.authOverridden && r.Method != http.MethodOptions {
+		return e.auths
+	}
+	if rt := e.route(r, relPath); rt != nil {
+		h, ok := rt.lookup(r.Method)
+		if !ok && r.Method == http.MethodOptions {
+			return nil
+		}
+		if ok && h.overrideAuth {
+			return h.auths
+		}
+	}
+	return e.auths
+}
+
+// route 返回处理relPath的路由,没有匹配或ServeMux需要重定向(如补全末尾的/)时返回nil,
+// 重定向的请求不会到达目标路由,使用入口的配置
+func (e *Entry) route(r *http.Request, relPath string) *route {
+	lookup := *r
+	u := *r.URL
+	u.Path, u.RawPath = relPath, ""
+	lookup.URL = &u
+	h, _ := e.router.Handler(&lookup)
+	rt, _ := h.(*route)
+	return rt
 }
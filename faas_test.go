@@ -0,0 +1,124 @@
+package faas
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunContextBindError(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	var logs bytes.Buffer
+	app := New()
+	app.Addr = ln.Addr().String()
+	app.Logger = slog.New(slog.NewTextHandler(&logs, nil))
+	var started, stopped bool
+	app.OnStart(func(ctx context.Context) error {
+		started = true
+		return nil
+	})
+	app.OnStop(func(ctx context.Context) error {
+		stopped = true
+		return nil
+	})
+	if err := app.RunContext(context.Background()); err == nil {
+		t.Fatal("RunContext succeeded on a used address")
+	}
+	if !started || !stopped {
+		t.Errorf("start hook ran %v, stop hook ran %v, want both", started, stopped)
+	}
+	if app.Ready() {
+		t.Error("app is ready after a bind failure")
+	}
+	if strings.Contains(logs.String(), "server listening") {
+		t.Errorf("logged listening after a bind failure:\n%s", logs.String())
+	}
+}
+
+func TestRunContextMetricsBindError(t *testing.T) {
+	used, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer used.Close()
+	// 取一个空闲端口作为主服务的地址
+	free, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := free.Addr().String()
+	free.Close()
+
+	app := New()
+	app.Addr = addr
+	app.MetricsAddr = used.Addr().String()
+	app.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	err = app.RunContext(context.Background())
+	if err == nil || !strings.HasPrefix(err.Error(), "metrics server: ") {
+		t.Fatalf("RunContext error = %v, want a metrics server error", err)
+	}
+	if app.Ready() {
+		t.Error("app is ready after a metrics bind failure")
+	}
+	// 主服务不能在返回后继续运行
+	if conn, err := net.Dial("tcp", addr); err == nil {
+		conn.Close()
+		t.Errorf("main server is still listening on %s", addr)
+	}
+}
+
+func TestRunContextServe(t *testing.T) {
+	free, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := free.Addr().String()
+	free.Close()
+
+	app := New()
+	app.Addr = addr
+	app.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	app.HandleFunc("api", "path", "/ping", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("pong"))
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- app.RunContext(ctx)
+	}()
+	// 就绪时端口已经在监听
+	deadline := time.Now().Add(5 * time.Second)
+	for !app.Ready() {
+		if time.Now().After(deadline) {
+			t.Fatal("app did not become ready")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	resp, err := http.Get("http://" + addr + "/ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "pong" {
+		t.Errorf("GET /ping = %q, want pong", body)
+	}
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Errorf("RunContext: %v", err)
+	}
+	if app.Ready() {
+		t.Error("app is ready after shutdown")
+	}
+}
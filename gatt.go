@@ -2,24 +2,21 @@ package faas
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 )
 
-var gattHandlerMap map[string]func(http.ResponseWriter, *http.Request, *Context)
-
-func GattEntry(entryName, gattPath string, handler func(http.ResponseWriter, *http.Request, *Context), resDir string) {
+func (a *App) GattEntry(entryName, gattPath string, handler func(http.ResponseWriter, *http.Request, *Context), resDir string) {
 	var directoryTreeCache []byte
 	var treeCacheErr error
-	log.Printf("Registering GattEntry path: %s\n", gattPath)
+	a.logger().Info("registering gatt entry", "entry", entryName, "path", gattPath)
 
-	if gattHandlerMap != nil {
+	if a.gattHandlerMap != nil {
 		panic("GattEntry can only be called once")
 	}
-	gattHandlerMap = make(map[string]func(http.ResponseWriter, *http.Request, *Context))
+	a.gattHandlerMap = make(map[string]func(http.ResponseWriter, *http.Request, *Context))
 
 	absPath := filepath.Join(FAAS.WorkDir, resDir)
 	tree, err := buildDirectoryTree(absPath)
@@ -31,7 +28,7 @@ func GattEntry(entryName, gattPath string, handler func(http.ResponseWriter, *ht
 
 	wrapHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		c, _ := r.Context().Value(contextKey).(*Context)
-		log.Printf("GattHandler relPath: %v,subPath: %v, r.URL.RawQuery: %v", c.RelPath, c.SubPath, r.URL.RawQuery)
+		c.Logger().Debug("gatt request", "sub_path", c.SubPath, "query", r.URL.RawQuery)
 		path := c.SubPath
 		if path == "" {
 			w.Header().Set("Content-Type", "application/json")
@@ -43,10 +40,16 @@ func GattEntry(entryName, gattPath string, handler func(http.ResponseWriter, *ht
 		} else if f := r.URL.Query().Get("fn"); f != "" && strings.HasSuffix(path, ".att") {
 			w.Header().Set("Content-Type", "application/json")
 			c.Fn = strings.TrimPrefix(path, "/") + "@" + f
-			if h, ok := gattHandlerMap[c.Fn]; ok {
+			r, span := startSpan(r, "gatt fn")
+			defer span.End()
+			span.SetAttributes("faas.fn", c.Fn)
+			if h, ok := a.gattHandlerMap[c.Fn]; ok {
 				h(w, r, c)
-			} else if h, ok := gattHandlerMap["*"]; ok {
+				a.metrics.observeGattFn(c.Fn, responseStatus(w))
+			} else if h, ok := a.gattHandlerMap["*"]; ok {
 				h(w, r, c)
+				// 由*处理的fn来自请求参数,不作为标签
+				a.metrics.observeGattFn("*", responseStatus(w))
 			} else {
 				http.Error(w, "Not Found", http.StatusNotFound)
 			}
@@ -54,26 +57,26 @@ func GattEntry(entryName, gattPath string, handler func(http.ResponseWriter, *ht
 			serveStatic(w, r, c, absPath, handler)
 		}
 	})
-	HandleFunc(entryName, "path", gattPath, wrapHandler)
-	HandleFunc(entryName, "prefix", gattPath, wrapHandler)
+	a.HandleFunc(entryName, "path", gattPath, wrapHandler)
+	a.HandleFunc(entryName, "prefix", gattPath, wrapHandler)
 }
 
-func RegisterGattFnHandler(fn string, handler func(http.ResponseWriter, *http.Request, *Context)) {
-	log.Printf("Registering gatt fn: %s\n", fn)
-	if gattHandlerMap == nil {
+func (a *App) RegisterGattFnHandler(fn string, handler func(http.ResponseWriter, *http.Request, *Context)) {
+	a.logger().Info("registering gatt fn", "fn", fn)
+	if a.gattHandlerMap == nil {
 		panic("Before registering an GattFnHandler, you must first call GattEntry")
 	}
-	if _, ok := gattHandlerMap[fn]; ok {
+	if _, ok := a.gattHandlerMap[fn]; ok {
 		panic(fn + " has already been registered.")
 	}
-	gattHandlerMap[fn] = handler
+	a.gattHandlerMap[fn] = handler
 }
 
 func StaticHandler(handler func(http.ResponseWriter, *http.Request, *Context), resDir string) http.HandlerFunc {
 	absPath := filepath.Join(FAAS.WorkDir, resDir)
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		c, _ := r.Context().Value(contextKey).(*Context)
-		log.Printf("StaticHandler relPath: %v,subPath: %v", c.RelPath, c.SubPath)
+		c.Logger().Debug("static request", "sub_path", c.SubPath)
 		serveStatic(w, r, c, absPath, handler)
 	})
 }
@@ -0,0 +1,179 @@
+package faas
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// healthCheck 通过RegisterCheck注册的健康检查
+type healthCheck struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// CheckResult 单个健康检查的结果
+type CheckResult struct {
+	Status     string  `json:"status"`
+	Error      string  `json:"error,omitempty"`
+	DurationMs float64 `json:"duration_ms"`
+}
+
+// HealthReport 健康检查的汇总结果,所有检查通过时Status为up
+type HealthReport struct {
+	Status string                 `json:"status"`
+	Ready  bool                   `json:"ready"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// envPath 读取路径类型的环境变量,为空时返回def,为off时返回空表示关闭
+func envPath(name, def string) string {
+	switch p := os.Getenv(name); p {
+	case "":
+		return def
+	case "off":
+		return ""
+	default:
+		return p
+	}
+}
+
+// RegisterCheck 注册健康检查,就绪和健康检查接口会执行所有检查并汇总结果,
+// 返回错误或panic视为不通过,每个检查的执行时长受HealthCheckTimeout限制
+func (a *App) RegisterCheck(name string, check func(ctx context.Context) error) {
+	a.logger().Info("registering health check", "name", name)
+	a.checksMu.Lock()
+	defer a.checksMu.Unlock()
+	for _, c := range a.checks {
+		if c.name == name {
+			panic("health check " + name + " has already been registered.")
+		}
+	}
+	a.checks = append(a.checks, healthCheck{name: name, fn: check})
+}
+
+// Ready 返回服务是否就绪:启动钩子已全部完成且未开始退出
+func (a *App) Ready() bool {
+	return a.ready.Load()
+}
+
+// CheckHealth 并发执行所有健康检查并汇总结果
+func (a *App) CheckHealth(ctx context.Context) HealthReport {
+	a.checksMu.Lock()
+	checks := append([]healthCheck(nil), a.checks...)
+	a.checksMu.Unlock()
+
+	report := HealthReport{Status: "up", Ready: a.Ready()}
+	if len(checks) == 0 {
+		return report
+	}
+	if a.HealthCheckTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.HealthCheckTimeout)
+		defer cancel()
+	}
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = runCheck(ctx, c)
+		}()
+	}
+	wg.Wait()
+	report.Checks = make(map[string]CheckResult, len(checks))
+	for i, c := range checks {
+		report.Checks[c.name] = results[i]
+		if results[i].Status != "up" {
+			report.Status = "down"
+		}
+	}
+	return report
+}
+
+// runCheck 执行单个检查,超时未返回时视为不通过
+func runCheck(ctx context.Context, c healthCheck) CheckResult {
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- safeCall("check", c.name, func() error {
+			return c.fn(ctx)
+		})
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	result := CheckResult{Status: "up", DurationMs: float64(time.Since(start).Microseconds()) / 1000}
+	if err != nil {
+		var fe *FuncletError
+		if errors.As(err, &fe) {
+			err = fe.Err
+		}
+		result.Status, result.Error = "down", err.Error()
+	}
+	return result
+}
+
+// LiveHandler 返回存活检查的handler,进程能处理请求即响应200
+func (a *App) LiveHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, http.StatusOK, HealthReport{Status: "up", Ready: a.Ready()})
+	})
+}
+
+// ReadyHandler 返回就绪检查的handler,启动钩子完成前、退出过程中或有检查不通过时响应503
+func (a *App) ReadyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.Ready() {
+			writeHealth(w, http.StatusServiceUnavailable, HealthReport{Status: "down"})
+			return
+		}
+		report := a.CheckHealth(r.Context())
+		writeHealth(w, healthStatus(report), report)
+	})
+}
+
+// HealthHandler 返回健康检查的handler,输出所有检查的结果,有检查不通过时响应503
+func (a *App) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := a.CheckHealth(r.Context())
+		writeHealth(w, healthStatus(report), report)
+	})
+}
+
+// healthHandler 返回路径对应的健康检查handler,不是健康检查路径时返回nil
+func (a *App) healthHandler(path string) http.Handler {
+	switch path {
+	case "":
+		return nil
+	case a.LivePath:
+		return a.LiveHandler()
+	case a.ReadyPath:
+		return a.ReadyHandler()
+	case a.HealthPath:
+		return a.HealthHandler()
+	}
+	return nil
+}
+
+func healthStatus(report HealthReport) int {
+	if report.Status != "up" {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusOK
+}
+
+func writeHealth(w http.ResponseWriter, status int, report HealthReport) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
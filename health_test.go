@@ -0,0 +1,174 @@
+package faas
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// getHealth 请求健康检查接口并解析结果
+func getHealth(t *testing.T, app *App, path string) (int, HealthReport) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("GET %s Content-Type = %q, want application/json", path, ct)
+	}
+	var report HealthReport
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("GET %s: %v: %s", path, err, w.Body.String())
+	}
+	return w.Code, report
+}
+
+func TestHealthChecks(t *testing.T) {
+	app := New()
+	app.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	app.HealthCheckTimeout = 50 * time.Millisecond
+	app.ready.Store(true)
+
+	status, report := getHealth(t, app, "/healthz")
+	if status != http.StatusOK || report.Status != "up" || report.Checks != nil {
+		t.Errorf("no checks: %d %+v, want 200 up", status, report)
+	}
+
+	app.RegisterCheck("db", func(ctx context.Context) error {
+		return nil
+	})
+	status, report = getHealth(t, app, "/healthz")
+	if status != http.StatusOK || report.Status != "up" || report.Checks["db"].Status != "up" {
+		t.Errorf("passing check: %d %+v, want 200 up", status, report)
+	}
+
+	app.RegisterCheck("cache", func(ctx context.Context) error {
+		return errors.New("connection refused")
+	})
+	app.RegisterCheck("queue", func(ctx context.Context) error {
+		panic("boom")
+	})
+	// 不响应ctx的检查在超时后视为不通过
+	release := make(chan struct{})
+	defer close(release)
+	app.RegisterCheck("slow", func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+	start := time.Now()
+	for _, path := range []string{"/healthz", "/readyz"} {
+		status, report = getHealth(t, app, path)
+		if status != http.StatusServiceUnavailable || report.Status != "down" || !report.Ready {
+			t.Errorf("GET %s = %d %+v, want 503 down", path, status, report)
+		}
+		want := map[string]CheckResult{
+			"db":    {Status: "up"},
+			"cache": {Status: "down", Error: "connection refused"},
+			"queue": {Status: "down", Error: "panic: boom"},
+			"slow":  {Status: "down", Error: context.DeadlineExceeded.Error()},
+		}
+		for name, w := range want {
+			got := report.Checks[name]
+			if got.Status != w.Status || got.Error != w.Error {
+				t.Errorf("GET %s check %s = %+v, want %+v", path, name, got, w)
+			}
+		}
+		if d := report.Checks["slow"].DurationMs; d < 50 {
+			t.Errorf("GET %s slow check took %vms, want at least the 50ms timeout", path, d)
+		}
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("health checks took %v, want the timeout to bound them", d)
+	}
+
+	// 存活检查不执行检查
+	status, report = getHealth(t, app, "/livez")
+	if status != http.StatusOK || report.Status != "up" || report.Checks != nil {
+		t.Errorf("GET /livez = %d %+v, want 200 up without checks", status, report)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a duplicate check did not panic")
+		}
+	}()
+	app.RegisterCheck("db", func(ctx context.Context) error {
+		return nil
+	})
+}
+
+func TestReadyz(t *testing.T) {
+	free, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := free.Addr().String()
+	free.Close()
+
+	app := New()
+	app.Addr = addr
+	app.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	app.RegisterCheck("db", func(ctx context.Context) error {
+		return nil
+	})
+	starting := make(chan struct{})
+	release := make(chan struct{})
+	app.OnStart(func(ctx context.Context) error {
+		close(starting)
+		<-release
+		return nil
+	})
+
+	status, report := getHealth(t, app, "/readyz")
+	if status != http.StatusServiceUnavailable || report.Status != "down" || report.Ready {
+		t.Errorf("before start: %d %+v, want 503 down", status, report)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- app.RunContext(ctx)
+	}()
+	// 启动钩子未完成时仍未就绪,存活检查不受影响
+	<-starting
+	status, report = getHealth(t, app, "/readyz")
+	if status != http.StatusServiceUnavailable || report.Ready {
+		t.Errorf("during start hook: %d %+v, want 503", status, report)
+	}
+	if status, _ = getHealth(t, app, "/livez"); status != http.StatusOK {
+		t.Errorf("GET /livez during start hook = %d, want 200", status)
+	}
+	close(release)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !app.Ready() {
+		if time.Now().After(deadline) {
+			t.Fatal("app did not become ready")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	resp, err := http.Get("http://" + addr + "/readyz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report = HealthReport{}
+	err = json.NewDecoder(resp.Body).Decode(&report)
+	resp.Body.Close()
+	if err != nil || resp.StatusCode != http.StatusOK || report.Status != "up" || !report.Ready || report.Checks["db"].Status != "up" {
+		t.Errorf("after start: %d %+v %v, want 200 up", resp.StatusCode, report, err)
+	}
+
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Errorf("RunContext: %v", err)
+	}
+	status, report = getHealth(t, app, "/readyz")
+	if status != http.StatusServiceUnavailable || report.Ready {
+		t.Errorf("after shutdown: %d %+v, want 503", status, report)
+	}
+}
@@ -0,0 +1,169 @@
+package faas
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+)
+
+// Validator 请求结构体实现此接口时,JSONHandler在解码后调用Validate,返回错误时响应400
+type Validator interface {
+	Validate() error
+}
+
+// JSONHandler 将func(*Context, *Req) (*Resp, error)适配为http.HandlerFunc:
+// 请求体按JSON解码到Req,带query和path标签的字段分别从查询参数和路径参数取值,
+// 返回值按JSON编码,返回nil时响应204,错误按StatusCode映射状态码并以{"error": "..."}响应
+func JSONHandler[Req, Resp any](handler func(*Context, *Req) (*Resp, error)) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, _ := r.Context().Value(contextKey).(*Context)
+		req := new(Req)
+		if err := decodeJSONRequest(r, req); err != nil {
+			status := http.StatusBadRequest
+			var mbe *http.MaxBytesError
+			if errors.As(err, &mbe) {
+				status = http.StatusRequestEntityTooLarge
+			}
+			writeJSONError(w, r, &HTTPError{Status: status, Message: err.Error(), Err: err})
+			return
+		}
+		if v, ok := any(req).(Validator); ok {
+			if err := v.Validate(); err != nil {
+				var he *HTTPError
+				if !errors.As(err, &he) {
+					err = &HTTPError{Status: http.StatusBadRequest, Message: err.Error(), Err: err}
+				}
+				writeJSONError(w, r, err)
+				return
+			}
+		}
+		resp, err := handler(c, req)
+		if err != nil {
+			writeJSONError(w, r, err)
+			return
+		}
+		if resp == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		WriteJSON(w, http.StatusOK, resp)
+	})
+}
+
+// WriteJSON 以application/json响应v
+func WriteJSON(w http.ResponseWriter, status int, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_, err = w.Write(body)
+	return err
+}
+
+// writeJSONError 与WriteError相同,但以JSON响应错误内容
+func writeJSONError(w http.ResponseWriter, r *http.Request, err error) {
+	status, msg := resolveError(r, err)
+	if responseStarted(w, r, status, err) {
+		return
+	}
+	WriteJSON(w, status, map[string]string{"error": msg})
+}
+
+func decodeJSONRequest(r *http.Request, req any) error {
+	if r.Body != nil && r.Body != http.NoBody {
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil && err != io.EOF {
+			return fmt.Errorf("bad json body: %w", err)
+		}
+	}
+	rv := reflect.ValueOf(req).Elem()
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	query := r.URL.Query()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if name := field.Tag.Get("path"); name != "" {
+			if v := r.PathValue(name); v != "" {
+				if err := setField(rv.Field(i), []string{v}); err != nil {
+					return fmt.Errorf("bad path param %s: %w", name, err)
+				}
+			}
+		}
+		if name := field.Tag.Get("query"); name != "" {
+			if v, ok := query[name]; ok {
+				if err := setField(rv.Field(i), v); err != nil {
+					return fmt.Errorf("bad query param %s: %w", name, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// setField 将字符串值设置到基础类型或基础类型切片的字段
+func setField(fv reflect.Value, values []string) error {
+	if fv.Kind() == reflect.Slice {
+		slice := reflect.MakeSlice(fv.Type(), len(values), len(values))
+		for i, v := range values {
+			if err := setValue(slice.Index(i), v); err != nil {
+				return err
+			}
+		}
+		fv.Set(slice)
+		return nil
+	}
+	if fv.Kind() == reflect.Pointer {
+		ptr := reflect.New(fv.Type().Elem())
+		if err := setValue(ptr.Elem(), values[0]); err != nil {
+			return err
+		}
+		fv.Set(ptr)
+		return nil
+	}
+	return setValue(fv, values[0])
+}
+
+func setValue(fv reflect.Value, v string) error {
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(v)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(v, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(v, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(v, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported field type %s", fv.Type())
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package faas
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type jsonItem struct {
+	ID    int      `path:"id" json:"id"`
+	Tags  []string `query:"tag" json:"tags,omitempty"`
+	Limit *uint8   `query:"limit" json:"limit,omitempty"`
+	Ratio float32  `query:"ratio" json:"ratio,omitempty"`
+	Debug bool     `query:"debug" json:"debug,omitempty"`
+	Name  string   `json:"name"`
+	// 未导出的字段即使带标签也不填充
+	secret string `query:"secret"`
+}
+
+func (it *jsonItem) Validate() error {
+	switch it.Name {
+	case "invalid":
+		return errors.New("name is invalid")
+	case "forbidden":
+		return ErrForbidden
+	}
+	return nil
+}
+
+type jsonUnsupported struct {
+	Filter map[string]string `query:"filter"`
+	Ptrs   []*int            `query:"ptr"`
+}
+
+func TestJSONHandler(t *testing.T) {
+	app := New()
+	app.HandleFunc("api", "path", "/items/{id}", JSONHandler(func(c *Context, it *jsonItem) (*jsonItem, error) {
+		switch it.Name {
+		case "missing":
+			return nil, ErrNotFound
+		case "empty":
+			return nil, nil
+		case "boom":
+			return nil, errors.New("db password is hunter2")
+		}
+		if it.secret != "" {
+			return nil, errors.New("unexported field was filled")
+		}
+		return it, nil
+	}), MaxBodyBytes(64))
+	app.HandleFunc("api", "path", "/unsupported", JSONHandler(func(c *Context, req *jsonUnsupported) (*jsonUnsupported, error) {
+		return req, nil
+	}))
+	app.HandleFunc("api", "path", "/sum", JSONHandler(func(c *Context, nums *[]int) (*int, error) {
+		sum := 0
+		for _, n := range *nums {
+			sum += n
+		}
+		return &sum, nil
+	}))
+
+	tests := []struct {
+		name   string
+		url    string
+		body   string
+		status int
+		want   string
+	}{
+		{"body and params", "/items/7?tag=a&tag=b&limit=5&ratio=0.5&debug=true&secret=x", `{"name":"pen"}`, http.StatusOK,
+			`{"id":7,"tags":["a","b"],"limit":5,"ratio":0.5,"debug":true,"name":"pen"}`},
+		{"params override body", "/items/7", `{"id":1,"name":"pen"}`, http.StatusOK, `{"id":7,"name":"pen"}`},
+		{"empty body", "/items/7", "", http.StatusOK, `{"id":7,"name":""}`},
+		{"nil response", "/items/7", `{"name":"empty"}`, http.StatusNoContent, ""},
+		{"body too large", "/items/7", `{"name":"` + strings.Repeat("x", 64) + `"}`, http.StatusRequestEntityTooLarge, `{"error":"bad json body: http: request body too large"}`},
+		{"bad json", "/items/7", `{"name":`, http.StatusBadRequest, `{"error":"bad json body: unexpected EOF"}`},
+		{"wrong json type", "/items/7", `{"name":1}`, http.StatusBadRequest, ""},
+		{"bad path param", "/items/x", `{}`, http.StatusBadRequest, ""},
+		{"bad query int", "/items/7?limit=256", `{}`, http.StatusBadRequest, ""},
+		{"bad query bool", "/items/7?debug=maybe", `{}`, http.StatusBadRequest, ""},
+		{"validation error", "/items/7", `{"name":"invalid"}`, http.StatusBadRequest, `{"error":"name is invalid"}`},
+		{"validation http error", "/items/7", `{"name":"forbidden"}`, http.StatusForbidden, `{"error":"Forbidden"}`},
+		{"handler sentinel", "/items/7", `{"name":"missing"}`, http.StatusNotFound, `{"error":"Not Found"}`},
+		{"handler error", "/items/7", `{"name":"boom"}`, http.StatusInternalServerError, `{"error":"Internal Server Error"}`},
+		{"unsupported map field", "/unsupported?filter=a", "", http.StatusBadRequest, `{"error":"bad query param filter: unsupported field type map[string]string"}`},
+		{"unsupported slice element", "/unsupported?ptr=1", "", http.StatusBadRequest, `{"error":"bad query param ptr: unsupported field type *int"}`},
+		{"unsupported field not in query", "/unsupported", "", http.StatusOK, `{"Filter":null,"Ptrs":null}`},
+		{"non-struct request", "/sum?x=1", `[1,2,3]`, http.StatusOK, `6`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, tt.url, strings.NewReader(tt.body))
+			// 不带Content-Length,超出限制在解码时发现
+			r.ContentLength = -1
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, r)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body.String())
+			}
+			if tt.status != http.StatusNoContent && !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+				t.Errorf("Content-Type = %q", w.Header().Get("Content-Type"))
+			}
+			if tt.want != "" && w.Body.String() != tt.want {
+				t.Errorf("body = %s, want %s", w.Body.String(), tt.want)
+			}
+			if tt.status == http.StatusNoContent && w.Body.Len() != 0 {
+				t.Errorf("204 with body %q", w.Body.String())
+			}
+		})
+	}
+}
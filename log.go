@@ -0,0 +1,114 @@
+package faas
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// RequestIDHeader 请求ID的请求头,响应中同样带上
+const RequestIDHeader = "X-Request-Id"
+
+// requestIDHeaders 网关可能设置的请求ID请求头,按顺序取第一个合法的值
+var requestIDHeaders = []string{RequestIDHeader, "Faas-Request-Id"}
+
+// newLogger 按环境变量SU_LOG_FORMAT(text|json)和SU_LOG_LEVEL(debug|info|warn|error)创建Logger,
+// 都为空时返回nil,使用slog.Default()
+func newLogger() *slog.Logger {
+	format, level := os.Getenv("SU_LOG_FORMAT"), os.Getenv("SU_LOG_LEVEL")
+	if format == "" && level == "" {
+		return nil
+	}
+	var lv slog.Level
+	if err := lv.UnmarshalText([]byte(level)); err != nil {
+		lv = slog.LevelInfo
+	}
+	opts := &slog.HandlerOptions{Level: lv}
+	if format == "json" {
+		return slog.New(slog.NewJSONHandler(os.Stderr, opts))
+	}
+	return slog.New(slog.NewTextHandler(os.Stderr, opts))
+}
+
+// logger 返回App使用的Logger,未设置时为slog.Default()
+func (a *App) logger() *slog.Logger {
+	if a.Logger != nil {
+		return a.Logger
+	}
+	return slog.Default()
+}
+
+// requestID 沿用网关传入的请求ID,没有或不合法时生成新的
+func requestID(r *http.Request) string {
+	for _, h := range requestIDHeaders {
+		if id := r.Header.Get(h); validRequestID(id) {
+			return id
+		}
+	}
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// validRequestID 只接受不超过128个字符的可见ASCII字符,避免日志注入
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// Logger 返回带有入口、路径、gatt函数名和请求ID的Logger
+func (c *Context) Logger() *slog.Logger {
+	var l *slog.Logger
+	if c.app != nil {
+		l = c.app.logger()
+	} else {
+		l = slog.Default()
+	}
+	attrs := []any{slog.String("entry", c.Entry), slog.String("path", c.RelPath)}
+	if c.Fn != "" {
+		attrs = append(attrs, slog.String("fn", c.Fn))
+	}
+	if c.RequestID != "" {
+		attrs = append(attrs, slog.String("request_id", c.RequestID))
+	}
+	if c.span != nil {
+		attrs = append(attrs, slog.String("trace_id", c.span.sc.TraceID.String()))
+	}
+	return l.With(attrs...)
+}
+
+// accessLog 请求结束后记录一行访问日志
+func (a *App) accessLog(c *Context, r *http.Request, status int, start time.Time) {
+	level := slog.LevelInfo
+	if status >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+	c.Logger().LogAttrs(context.Background(), level, "access",
+		slog.String("method", r.Method),
+		slog.String("uri", c.oriPath),
+		slog.Int("status", status),
+		slog.Int("size", c.w.Size()),
+		slog.Duration("duration", time.Since(start)),
+		slog.String("remote", remoteIP(r)),
+	)
+}
+
+func remoteIP(r *http.Request) string {
+	if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+		ip, _, _ := strings.Cut(fwd, ",")
+		return strings.TrimSpace(ip)
+	}
+	return r.RemoteAddr
+}
@@ -0,0 +1,215 @@
+package faas
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// 请求耗时的分桶,单位秒
+	requestBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+	// 定时函数耗时的分桶,单位秒
+	timingBuckets = []float64{0.1, 0.5, 1, 5, 10, 30, 60, 300, 600, 1800, 3600}
+)
+
+type histogram struct {
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+func (h *histogram) observe(v float64) {
+	for i, b := range h.buckets {
+		if v <= b {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// metrics 运行时的指标,以Prometheus文本格式输出
+type metrics struct {
+	mu       sync.Mutex
+	requests map[string]*histogram
+	messages map[string]uint64
+	gattFns  map[string]uint64
+	timings  map[string]*histogram
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		requests: make(map[string]*histogram),
+		messages: make(map[string]uint64),
+		gattFns:  make(map[string]uint64),
+		timings:  make(map[string]*histogram),
+	}
+}
+
+// labels 按name="value"格式拼接标签,作为指标的key
+func labels(kv ...string) string {
+	var b strings.Builder
+	for i := 0; i < len(kv); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(kv[i])
+		b.WriteString(`="`)
+		b.WriteString(escapeLabel(kv[i+1]))
+		b.WriteByte('"')
+	}
+	return b.String()
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s)
+}
+
+// observeRequest 记录一次请求,msg入口记为消息,其他入口记录耗时
+func (m *metrics) observeRequest(entry, pattern string, status int, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	code := strconv.Itoa(status)
+	if entry == "msg" {
+		typ, path, _ := strings.Cut(pattern, "/")
+		m.messages[labels("type", typ, "path", "/"+path, "status", code)]++
+		return
+	}
+	key := labels("entry", entry, "route", pattern, "status", code)
+	h, ok := m.requests[key]
+	if !ok {
+		h = newHistogram(requestBuckets)
+		m.requests[key] = h
+	}
+	h.observe(d.Seconds())
+}
+
+func (m *metrics) observeGattFn(fn string, status int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gattFns[labels("fn", fn, "status", strconv.Itoa(status))]++
+}
+
+func (m *metrics) observeTiming(name string, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := labels("timing", name)
+	h, ok := m.timings[key]
+	if !ok {
+		h = newHistogram(timingBuckets)
+		m.timings[key] = h
+	}
+	h.observe(d.Seconds())
+}
+
+// observe 请求结束后记录指标和访问日志,并结束请求的server span
+func (a *App) observe(c *Context, r *http.Request, start time.Time) {
+	status := c.w.Status()
+	if status == 0 {
+		status = http.StatusOK
+	}
+	a.endSpan(c, r, status)
+	// 未知入口的名称来自请求头,不作为标签
+	entry := ""
+	if _, ok := a.entryMap[c.Entry]; ok {
+		entry = c.Entry
+	}
+	a.metrics.observeRequest(entry, c.route, status, time.Since(start))
+	if a.AccessLog {
+		a.accessLog(c, r, status, start)
+	}
+}
+
+// responseStatus 返回已写入的状态码,未写入时为200
+func responseStatus(w http.ResponseWriter) int {
+	if rw, ok := w.(ResponseWriter); ok && rw.Status() != 0 {
+		return rw.Status()
+	}
+	return http.StatusOK
+}
+
+// MetricsHandler 返回以Prometheus文本格式输出运行时指标的handler,
+// 设置了MetricsPath或MetricsAddr时由Run自动提供,也可自行挂载
+func (a *App) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		a.writeMetrics(w)
+	})
+}
+
+func (a *App) writeMetrics(w io.Writer) {
+	m := a.metrics
+	m.mu.Lock()
+	writeHistograms(w, "faas_http_request_duration_seconds", "HTTP request latency by entry, route pattern and status.", m.requests)
+	writeCounters(w, "faas_messages_total", "Messages handled by type, path and status.", m.messages)
+	writeCounters(w, "faas_gatt_fn_calls_total", "Gatt fn calls by fn and status.", m.gattFns)
+	writeHistograms(w, "faas_timing_duration_seconds", "Timing funclet run duration.", m.timings)
+	m.mu.Unlock()
+
+	stats := a.TimingStats()
+	series := []struct {
+		name, help, typ string
+		value           func(s TimingStats) int64
+	}{
+		{"faas_timing_runs_total", "Timing funclet runs.", "counter", func(s TimingStats) int64 { return s.Runs }},
+		{"faas_timing_failures_total", "Timing funclet runs that panicked.", "counter", func(s TimingStats) int64 { return s.Failures }},
+		{"faas_timing_skipped_total", "Timing funclet runs skipped by the concurrency policy.", "counter", func(s TimingStats) int64 { return s.Skipped }},
+		{"faas_timing_delayed_total", "Timing funclet runs delayed by the concurrency policy.", "counter", func(s TimingStats) int64 { return s.Delayed }},
+		{"faas_timing_running", "Timing funclet runs in progress.", "gauge", func(s TimingStats) int64 { return int64(s.Running) }},
+	}
+	for _, s := range series {
+		if len(stats) == 0 {
+			break
+		}
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", s.name, s.help, s.name, s.typ)
+		for _, st := range stats {
+			fmt.Fprintf(w, "%s{%s} %d\n", s.name, labels("timing", st.Name, "type", st.Type), s.value(st))
+		}
+	}
+}
+
+func writeCounters(w io.Writer, name, help string, counters map[string]uint64) {
+	if len(counters) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+	for _, key := range sortedKeys(counters) {
+		fmt.Fprintf(w, "%s{%s} %d\n", name, key, counters[key])
+	}
+}
+
+func writeHistograms(w io.Writer, name, help string, hs map[string]*histogram) {
+	if len(hs) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+	for _, key := range sortedKeys(hs) {
+		h := hs[key]
+		for i, b := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket{%s,le=\"%s\"} %d\n", name, key, strconv.FormatFloat(b, 'g', -1, 64), h.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, key, h.count)
+		fmt.Fprintf(w, "%s_sum{%s} %s\n", name, key, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(w, "%s_count{%s} %d\n", name, key, h.count)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,85 @@
+package faas
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	app := New()
+	app.MetricsPath = "/metrics"
+	app.HandleFunc("api", "path", "/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") == "0" {
+			http.Error(w, "no user", http.StatusNotFound)
+		}
+	})
+	for _, path := range []string{"/users/1", "/users/2", "/users/0", "/missing"} {
+		app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	// 未注册的入口不作为标签
+	r := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	r.Header.Set("Faas-Gateway-Name", "unknown")
+	app.ServeHTTP(httptest.NewRecorder(), r)
+
+	// 耗时固定的请求用于检查分桶
+	app.metrics.observeRequest("local", "/slow", 200, 30*time.Millisecond)
+	app.metrics.observeRequest("local", "/slow", 200, 2*time.Second)
+	app.metrics.observeRequest("msg", "mq/orders", 200, time.Second)
+	app.metrics.observeGattFn("quote\"back\\slash\nnewline", 200)
+
+	app.TimingFunc("once", "", func(env map[string]any) {
+		panic("boom")
+	})
+	var wg sync.WaitGroup
+	app.timings[0].start(context.Background(), &wg)
+	wg.Wait()
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if ct := w.Header().Get("Content-Type"); ct != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	out := w.Body.String()
+	name := app.timings[0].name
+	want := []string{
+		"# HELP faas_http_request_duration_seconds HTTP request latency by entry, route pattern and status.\n# TYPE faas_http_request_duration_seconds histogram\n",
+		`faas_http_request_duration_seconds_count{entry="api",route="/users/{id}",status="200"} 2`,
+		`faas_http_request_duration_seconds_count{entry="api",route="/users/{id}",status="404"} 1`,
+		`faas_http_request_duration_seconds_count{entry="api",route="",status="404"} 1`,
+		`faas_http_request_duration_seconds_count{entry="",route="",status="404"} 1`,
+		`faas_http_request_duration_seconds_bucket{entry="local",route="/slow",status="200",le="0.025"} 0
+faas_http_request_duration_seconds_bucket{entry="local",route="/slow",status="200",le="0.05"} 1
+faas_http_request_duration_seconds_bucket{entry="local",route="/slow",status="200",le="0.1"} 1
+faas_http_request_duration_seconds_bucket{entry="local",route="/slow",status="200",le="0.25"} 1
+faas_http_request_duration_seconds_bucket{entry="local",route="/slow",status="200",le="0.5"} 1
+faas_http_request_duration_seconds_bucket{entry="local",route="/slow",status="200",le="1"} 1
+faas_http_request_duration_seconds_bucket{entry="local",route="/slow",status="200",le="2.5"} 2
+faas_http_request_duration_seconds_bucket{entry="local",route="/slow",status="200",le="5"} 2
+faas_http_request_duration_seconds_bucket{entry="local",route="/slow",status="200",le="10"} 2
+faas_http_request_duration_seconds_bucket{entry="local",route="/slow",status="200",le="+Inf"} 2
+faas_http_request_duration_seconds_sum{entry="local",route="/slow",status="200"} 2.03
+faas_http_request_duration_seconds_count{entry="local",route="/slow",status="200"} 2
+`,
+		"# TYPE faas_messages_total counter\nfaas_messages_total{type=\"mq\",path=\"/orders\",status=\"200\"} 1\n",
+		"# TYPE faas_gatt_fn_calls_total counter\nfaas_gatt_fn_calls_total{fn=\"quote\\\"back\\\\slash\\nnewline\",status=\"200\"} 1\n",
+		`faas_timing_duration_seconds_count{timing="` + name + `"} 1`,
+		`faas_timing_runs_total{timing="` + name + `",type="once"} 1`,
+		`faas_timing_failures_total{timing="` + name + `",type="once"} 1`,
+		`faas_timing_skipped_total{timing="` + name + `",type="once"} 0`,
+		"# TYPE faas_timing_running gauge\nfaas_timing_running{timing=\"" + name + "\",type=\"once\"} 0\n",
+	}
+	for _, s := range want {
+		if !strings.Contains(out, s) {
+			t.Errorf("metrics output does not contain\n%s\n\ngot:\n%s", s, out)
+		}
+	}
+	// 指标接口自身不计入请求
+	if strings.Contains(out, `route="/metrics"`) {
+		t.Error("metrics endpoint is counted as a request")
+	}
+}
@@ -0,0 +1,78 @@
+package faas
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Middleware 包装handler,用于日志、监控、跨域、限流等横切逻辑
+type Middleware func(http.Handler) http.Handler
+
+type prefixMiddleware struct {
+	prefix      string
+	middlewares []Middleware
+}
+
+// chain 按顺序包装handler,mws[0]在最外层
+func chain(h http.Handler, mws []Middleware) http.Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+// Use 为整个入口添加中间件,在鉴权之后、路由之前执行
+func (a *App) Use(entryName string, mws ...Middleware) {
+	a.logger().Info("registering middleware", "entry", entryName, "count", len(mws))
+	entry := a.entry(entryName)
+	entry.middlewares = append(entry.middlewares, mws...)
+}
+
+// UsePrefix 为入口下路径等于prefix或以prefix/开头的请求添加中间件,在入口中间件之后执行
+func (a *App) UsePrefix(entryName, prefix string, mws ...Middleware) {
+	a.logger().Info("registering middleware", "entry", entryName, "prefix", prefix, "count", len(mws))
+	entry := a.entry(entryName)
+	prefix = strings.TrimSuffix(prefix, "/")
+	entry.prefixMiddlewares = append(entry.prefixMiddlewares, prefixMiddleware{prefix: prefix, middlewares: mws})
+}
+
+// UsePath 为入口下path类型注册的路由添加中间件,path与HandleFunc注册时相同,如/users/{id}
+func (a *App) UsePath(entryName, path string, mws ...Middleware) {
+	a.logger().Info("registering middleware", "entry", entryName, "path", path, "count", len(mws))
+	entry := a.entry(entryName)
+	if entry.pathMiddlewares == nil {
+		entry.pathMiddlewares = make(map[string][]Middleware)
+	}
+	entry.pathMiddlewares[path] = append(entry.pathMiddlewares[path], mws...)
+}
+
+// WithMiddleware 为单个funclet添加中间件,在入口、前缀和路径中间件之后执行
+func WithMiddleware(mws ...Middleware) RouteOption {
+	return func(o *routeOptions) {
+		o.middlewares = append(o.middlewares, mws...)
+	}
+}
+
+// dispatchMiddlewares 返回请求匹配的入口和前缀中间件,短前缀在外层
+func (e *Entry) dispatchMiddlewares(relPath string) []Middleware {
+	if len(e.prefixMiddlewares) == 0 {
+		return e.middlewares
+	}
+	mws := append([]Middleware(nil), e.middlewares...)
+	var matched []prefixMiddleware
+	for _, pm := range e.prefixMiddlewares {
+		if pm.prefix == "" || relPath == pm.prefix || strings.HasPrefix(relPath, pm.prefix+"/") {
+			matched = append(matched, pm)
+		}
+	}
+	// 稳定插入排序,相同前缀保持注册顺序
+	for i := 1; i < len(matched); i++ {
+		for j := i; j > 0 && len(matched[j].prefix) < len(matched[j-1].prefix); j-- {
+			matched[j], matched[j-1] = matched[j-1], matched[j]
+		}
+	}
+	for _, pm := range matched {
+		mws = append(mws, pm.middlewares...)
+	}
+	return mws
+}
@@ -0,0 +1,57 @@
+package faas
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddlewareOrder(t *testing.T) {
+	var trace []string
+	mw := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				trace = append(trace, name)
+				next.ServeHTTP(w, r)
+				trace = append(trace, "/"+name)
+			})
+		}
+	}
+	app := New()
+	app.HandleAuth("api", func(w http.ResponseWriter, r *http.Request, c *Context) {
+		trace = append(trace, "auth")
+	})
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		trace = append(trace, "handler")
+	}
+	app.HandleFunc("api", "path", "/api/v1/users/{id}", handler, WithMiddleware(mw("funclet1"), mw("funclet2")))
+	app.HandleFunc("api", "path", "/apiary", handler)
+	app.HandleFunc("api", "prefix", "/api/v1/files", handler)
+	// 长前缀先注册,执行时仍在短前缀之后
+	app.UsePrefix("api", "/api/v1/", mw("v1"))
+	app.UsePrefix("api", "/api", mw("api"))
+	app.UsePath("api", "/api/v1/users/{id}", mw("path"))
+	app.Use("api", mw("entry1"), mw("entry2"))
+	app.Use("api", mw("entry3"))
+
+	tests := []struct {
+		path  string
+		trace string
+	}{
+		{"/api/v1/users/1", "auth entry1 entry2 entry3 api v1 path funclet1 funclet2 handler /funclet2 /funclet1 /path /v1 /api /entry3 /entry2 /entry1"},
+		// 前缀按路径段匹配
+		{"/apiary", "auth entry1 entry2 entry3 handler /entry3 /entry2 /entry1"},
+		// 路径中间件只作用于注册的路由
+		{"/api/v1/files/a.txt", "auth entry1 entry2 entry3 api v1 handler /v1 /api /entry3 /entry2 /entry1"},
+		// 没有匹配的路由时入口和前缀中间件照常执行
+		{"/api/missing", "auth entry1 entry2 entry3 api /api /entry3 /entry2 /entry1"},
+	}
+	for _, tt := range tests {
+		trace = nil
+		app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if got := strings.Join(trace, " "); got != tt.trace {
+			t.Errorf("GET %s:\ngot  %s\nwant %s", tt.path, got, tt.trace)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package faas
+
+import (
+	"net/http"
+	"sync"
+)
+
+// openAPI 通过SetOpenAPI设置的OpenAPI文档
+type openAPI struct {
+	mu  sync.RWMutex
+	doc []byte
+}
+
+// SetOpenAPI 设置faasgen -openapi生成的OpenAPI文档,设置了OpenAPIPath时由ServeHTTP提供
+func (a *App) SetOpenAPI(doc []byte) {
+	a.openAPI.mu.Lock()
+	a.openAPI.doc = doc
+	a.openAPI.mu.Unlock()
+}
+
+// OpenAPIHandler 返回输出OpenAPI文档的handler,未设置文档时响应404
+func (a *App) OpenAPIHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a.openAPI.mu.RLock()
+		doc := a.openAPI.doc
+		a.openAPI.mu.RUnlock()
+		if len(doc) == 0 {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(doc)
+	})
+}
@@ -0,0 +1,134 @@
+package faas
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+)
+
+// FuncletError 描述funclet执行中发生的panic或错误
+type FuncletError struct {
+	//funclet类型:http、message、gatt、timing、start、stop
+	Kind string
+	//http类为请求路径,gatt为fn,其他为函数名
+	Name string
+	Err  error
+	//发生panic时的调用栈,普通错误为nil
+	Stack []byte
+	//http、message、gatt类的请求,其他为nil
+	Request *http.Request
+}
+
+func (e *FuncletError) Error() string {
+	return e.Kind + " funclet " + e.Name + ": " + e.Err.Error()
+}
+
+func (e *FuncletError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorReporter 接收funclet的panic和错误,可用于转发到外部告警系统
+type ErrorReporter interface {
+	Report(err *FuncletError)
+}
+
+// ErrorReporterFunc 将普通函数适配为ErrorReporter
+type ErrorReporterFunc func(err *FuncletError)
+
+func (f ErrorReporterFunc) Report(err *FuncletError) {
+	f(err)
+}
+
+// report 将错误交给ErrorReporter,未设置时连同调用栈写入App的Logger
+func (a *App) report(err *FuncletError) {
+	if a.ErrorReporter == nil {
+		logError(a.logger(), err)
+		return
+	}
+	a.ErrorReporter.Report(err)
+}
+
+// logError 记录FuncletError,请求类的错误带上请求ID
+func logError(l *slog.Logger, err *FuncletError) {
+	attrs := []any{"kind", err.Kind, "name", err.Name, "error", err.Err}
+	if err.Request != nil {
+		if c, ok := err.Request.Context().Value(contextKey).(*Context); ok && c.RequestID != "" {
+			attrs = append(attrs, "request_id", c.RequestID)
+		}
+	}
+	if err.Stack != nil {
+		l.Error("funclet panic", append(attrs, "stack", string(err.Stack))...)
+	} else {
+		l.Error("funclet error", attrs...)
+	}
+}
+
+// panicError 将recover得到的值转换为error
+func panicError(v any) error {
+	if err, ok := v.(error); ok {
+		return fmt.Errorf("panic: %w", err)
+	}
+	return fmt.Errorf("panic: %v", v)
+}
+
+// recoverHTTP 恢复请求处理中的panic,未写响应时返回500
+func (a *App) recoverHTTP(c *Context, r *http.Request) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	if v == http.ErrAbortHandler {
+		panic(v)
+	}
+	stack := debug.Stack()
+	if hp, ok := v.(*handlerPanic); ok {
+		v, stack = hp.value, hp.stack
+	}
+	kind, name := c.funclet()
+	a.report(&FuncletError{Kind: kind, Name: name, Err: panicError(v), Stack: stack, Request: r})
+	if c.w.Status() == 0 {
+		http.Error(c.w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+// funclet 返回处理当前请求的funclet类型和名称
+func (c *Context) funclet() (kind, name string) {
+	if c.Entry == "msg" {
+		return "message", c.RelPath
+	} else if c.Fn != "" {
+		return "gatt", c.Fn
+	}
+	return "http", c.RelPath
+}
+
+// safeCall 执行fn,将panic转换为带调用栈的FuncletError
+func safeCall(kind, name string, fn func() error) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &FuncletError{Kind: kind, Name: name, Err: panicError(v), Stack: debug.Stack()}
+		}
+	}()
+	if err = fn(); err != nil {
+		err = &FuncletError{Kind: kind, Name: name, Err: err}
+	}
+	return err
+}
+
+// reportError 上报safeCall返回的错误
+func (a *App) reportError(err error) {
+	var fe *FuncletError
+	if errors.As(err, &fe) {
+		a.report(fe)
+	} else if err != nil {
+		a.report(&FuncletError{Kind: "unknown", Err: err})
+	}
+}
+
+// funcName 返回函数的完整名称,用于日志和错误上报
+func funcName(fn any) string {
+	return runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()
+}
@@ -0,0 +1,46 @@
+package faas
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecoverHTTP(t *testing.T) {
+	var logs bytes.Buffer
+	app := New()
+	app.Logger = slog.New(slog.NewTextHandler(&logs, nil))
+	app.HandleFunc("api", "path", "/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	r := httptest.NewRequest(http.MethodGet, "/panic", nil)
+	r.Header.Set(RequestIDHeader, "req-1")
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, r)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", w.Code)
+	}
+	// 未设置ErrorReporter时写入App的Logger
+	out := logs.String()
+	for _, want := range []string{"funclet panic", "kind=http", "name=/panic", "request_id=req-1", "panic: boom", "stack="} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log does not contain %q:\n%s", want, out)
+		}
+	}
+
+	var reported []*FuncletError
+	app.ErrorReporter = ErrorReporterFunc(func(err *FuncletError) {
+		reported = append(reported, err)
+	})
+	logs.Reset()
+	app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if len(reported) != 1 || reported[0].Kind != "http" || reported[0].Stack == nil || reported[0].Err.Error() != "panic: boom" {
+		t.Errorf("reported %+v", reported)
+	}
+	if strings.Contains(logs.String(), "funclet panic") {
+		t.Errorf("panic logged although ErrorReporter is set:\n%s", logs.String())
+	}
+}
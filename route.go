@@ -0,0 +1,152 @@
+package faas
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RouteOption 路由的可选配置
+type RouteOption func(o *routeOptions)
+
+type routeOptions struct {
+	methods     []string
+	middlewares []Middleware
+	//overrideAuth为true时使用auths代替入口的鉴权链,auths为空表示不鉴权
+	overrideAuth bool
+	auths        []AuthFunc
+	timeout      time.Duration
+	maxBodyBytes int64
+	contentTypes []string
+}
+
+// Methods 限制路由只接受指定的HTTP方法,其他方法响应405并带Allow头,
+// 注册了GET时自动应答HEAD,OPTIONS自动应答204和Allow头。
+// 自动应答的OPTIONS跳过鉴权但经过中间件,CORS预检可由中间件处理;注册了OPTIONS的路由照常鉴权
+func Methods(methods ...string) RouteOption {
+	return func(o *routeOptions) {
+		for _, m := range methods {
+			o.methods = append(o.methods, strings.ToUpper(m))
+		}
+	}
+}
+
+// NoAuth 路由跳过入口的鉴权,如/health
+func NoAuth() RouteOption {
+	return func(o *routeOptions) {
+		o.overrideAuth = true
+		o.auths = nil
+	}
+}
+
+// WithAuth 路由使用指定的鉴权链代替入口的鉴权链
+func WithAuth(handlers ...func(http.ResponseWriter, *http.Request, *Context)) RouteOption {
+	return func(o *routeOptions) {
+		o.overrideAuth = true
+		for _, handler := range handlers {
+			o.auths = append(o.auths, authFunc(handler))
+		}
+	}
+}
+
+// WithAuthFunc 与WithAuth相同,鉴权函数返回错误时按错误的状态码拒绝请求,可与WithAuth混用,按选项顺序执行
+func WithAuthFunc(handlers ...AuthFunc) RouteOption {
+	return func(o *routeOptions) {
+		o.overrideAuth = true
+		o.auths = append(o.auths, handlers...)
+	}
+}
+
+type routeHandler struct {
+	handler http.Handler
+	//注册时的类型和路径,用于路由表
+	kind         string
+	path         string
+	overrideAuth bool
+	auths        []AuthFunc
+	timeout      time.Duration
+	maxBodyBytes int64
+	contentTypes []string
+}
+
+// route 同一路由模式下按HTTP方法分发,""表示不限方法的handler
+type route struct {
+	entry    *Entry
+	pattern  string
+	handlers map[string]*routeHandler
+}
+
+func (rt *route) add(methods []string, handler *routeHandler) {
+	if len(methods) == 0 {
+		methods = []string{""}
+	}
+	for _, m := range methods {
+		if _, ok := rt.handlers[m]; ok {
+			if m == "" {
+				m = "*"
+			}
+			panic("faas: " + m + " " + rt.pattern + " has already been registered")
+		}
+		rt.handlers[m] = handler
+	}
+}
+
+// allow 返回Allow头的内容
+func (rt *route) allow() string {
+	methods := []string{http.MethodOptions}
+	for m := range rt.handlers {
+		methods = append(methods, m)
+		if m == http.MethodGet {
+			if _, ok := rt.handlers[http.MethodHead]; !ok {
+				methods = append(methods, http.MethodHead)
+			}
+		}
+	}
+	sort.Strings(methods)
+	return strings.Join(methods, ", ")
+}
+
+// lookup 返回处理method的handler,HEAD可由GET处理
+func (rt *route) lookup(method string) (*routeHandler, bool) {
+	h, ok := rt.handlers[method]
+	if !ok && method == http.MethodHead {
+		h, ok = rt.handlers[http.MethodGet]
+	}
+	if !ok {
+		h, ok = rt.handlers[""]
+	}
+	return h, ok
+}
+
+func (rt *route) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if c, ok := r.Context().Value(contextKey).(*Context); ok {
+		c.route = rt.pattern
+	}
+	if h, ok := rt.lookup(r.Method); ok {
+		if !rt.checkBody(w, r, h) {
+			return
+		}
+		handler := chain(h.handler, rt.entry.pathMiddlewares[rt.pattern])
+		d := h.timeout
+		if d == 0 {
+			d = rt.entry.app.RequestTimeout
+		}
+		if d > 0 {
+			handler = timeoutHandler(handler, d)
+		}
+		r, span := startSpan(r, "handler "+rt.pattern)
+		defer span.End()
+		handler.ServeHTTP(w, r)
+		if status := responseStatus(w); status >= http.StatusInternalServerError {
+			span.RecordError(&HTTPError{Status: status})
+		}
+		return
+	}
+	w.Header().Set("Allow", rt.allow())
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+}
@@ -0,0 +1,142 @@
+package faas
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouteMethods(t *testing.T) {
+	app := New()
+	reply := func(body string) func(http.ResponseWriter, *http.Request) {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(body))
+		}
+	}
+	app.HandleFunc("api", "path", "/users/{id}", reply("get"), Methods("get"))
+	app.HandleFunc("api", "path", "/users/{id}", reply("update"), Methods("PUT", "PATCH"))
+	app.HandleFunc("api", "path", "/items", reply("post"), Methods("POST"))
+	app.HandleFunc("api", "path", "/items", reply("any"))
+	app.HandleFunc("api", "path", "/heads", reply("get"), Methods("GET"))
+	app.HandleFunc("api", "path", "/heads", reply("head"), Methods("HEAD"))
+
+	tests := []struct {
+		method, path string
+		status       int
+		body         string
+		allow        string
+	}{
+		{"GET", "/users/1", 200, "get", ""},
+		{"PUT", "/users/1", 200, "update", ""},
+		{"PATCH", "/users/1", 200, "update", ""},
+		// ResponseRecorder不丢弃HEAD的响应体,可以看出由哪个handler处理
+		{"HEAD", "/users/1", 200, "get", ""},
+		{"DELETE", "/users/1", 405, "Method Not Allowed\n", "GET, HEAD, OPTIONS, PATCH, PUT"},
+		{"OPTIONS", "/users/1", 204, "", "GET, HEAD, OPTIONS, PATCH, PUT"},
+		{"POST", "/items", 200, "post", ""},
+		{"DELETE", "/items", 200, "any", ""},
+		{"HEAD", "/heads", 200, "head", ""},
+		{"POST", "/heads", 405, "Method Not Allowed\n", "GET, HEAD, OPTIONS"},
+		{"GET", "/missing", 404, "404 page not found\n", ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.status || w.Body.String() != tt.body || w.Header().Get("Allow") != tt.allow {
+			t.Errorf("%s %s = %d %q Allow %q, want %d %q Allow %q", tt.method, tt.path,
+				w.Code, w.Body.String(), w.Header().Get("Allow"), tt.status, tt.body, tt.allow)
+		}
+	}
+}
+
+func TestRouteDuplicateMethod(t *testing.T) {
+	tests := []struct {
+		first, second []string
+	}{
+		{[]string{"GET"}, []string{"POST", "GET"}},
+		{nil, nil},
+	}
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("registering %v after %v does not panic", tt.second, tt.first)
+				}
+			}()
+			app := New()
+			h := func(http.ResponseWriter, *http.Request) {}
+			app.HandleFunc("api", "path", "/users", h, Methods(tt.first...))
+			app.HandleFunc("api", "path", "/users", h, Methods(tt.second...))
+		}()
+	}
+}
+
+func TestRoutePathParams(t *testing.T) {
+	app := New()
+	app.HandleFunc("api", "path", "/users/{id}/posts/{post}", WithContextHandler(func(w http.ResponseWriter, r *http.Request, c *Context) {
+		w.Write([]byte(c.Param("id") + " " + c.Param("post")))
+	}))
+	app.HandleFunc("api", "path", "/users/me/posts/{post}", WithContextHandler(func(w http.ResponseWriter, r *http.Request, c *Context) {
+		w.Write([]byte("me " + c.Param("post")))
+	}))
+	app.HandleFunc("api", "prefix", "/files/{bucket}", WithContextHandler(func(w http.ResponseWriter, r *http.Request, c *Context) {
+		w.Write([]byte(c.Param("bucket") + " " + c.SubPath))
+	}))
+
+	tests := []struct {
+		path, body string
+	}{
+		{"/users/42/posts/7", "42 7"},
+		{"/users/me/posts/7", "me 7"},
+		{"/files/b1/a/b.txt", "b1 /a/b.txt"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != http.StatusOK || w.Body.String() != tt.body {
+			t.Errorf("GET %s = %d %q, want 200 %q", tt.path, w.Code, w.Body.String(), tt.body)
+		}
+	}
+}
+
+func TestRouteOptionsSkipsAuth(t *testing.T) {
+	app := New()
+	app.HandleAuthFunc("api", func(w http.ResponseWriter, r *http.Request, c *Context) error {
+		return ErrUnauthorized
+	})
+	// CORS中间件在鉴权之后执行,预检请求同样经过
+	app.Use("api", func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+			next.ServeHTTP(w, r)
+		})
+	})
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	app.HandleFunc("api", "path", "/users/{id}", ok, Methods("GET", "PUT"))
+	app.HandleFunc("api", "path", "/explicit", ok, Methods("GET", "OPTIONS"))
+	app.HandleFunc("api", "path", "/any", ok)
+
+	tests := []struct {
+		method, path string
+		status       int
+		allow        string
+	}{
+		{"OPTIONS", "/users/1", 204, "GET, HEAD, OPTIONS, PUT"},
+		{"GET", "/users/1", 401, ""},
+		{"DELETE", "/users/1", 401, ""},
+		// 注册了OPTIONS或不限方法的路由由handler处理OPTIONS,照常鉴权
+		{"OPTIONS", "/explicit", 401, ""},
+		{"OPTIONS", "/any", 401, ""},
+		{"OPTIONS", "/missing", 401, ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.status || w.Header().Get("Allow") != tt.allow {
+			t.Errorf("%s %s = %d Allow %q, want %d Allow %q", tt.method, tt.path, w.Code, w.Header().Get("Allow"), tt.status, tt.allow)
+		}
+		if tt.status == 204 && w.Header().Get("Access-Control-Allow-Origin") != "*" {
+			t.Errorf("%s %s did not pass through the entry middleware", tt.method, tt.path)
+		}
+	}
+}
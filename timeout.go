@@ -0,0 +1,148 @@
+package faas
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Timeout 限制路由的处理时长,r.Context()在超时后被取消,未及时返回时响应504,
+// 为0时使用App.RequestTimeout,小于0时不限时。
+// 限时的handler的响应先写入缓冲,超时前返回后才发送给客户端,因此不适用于流式响应
+func Timeout(d time.Duration) RouteOption {
+	return func(o *routeOptions) {
+		o.timeout = d
+	}
+}
+
+// handlerPanic 限时的handler在另一个goroutine中panic时,携带原始调用栈交给recoverHTTP处理
+type handlerPanic struct {
+	value any
+	stack []byte
+}
+
+// timeoutHandler 与http.TimeoutHandler相同,但超时响应504并上报给ErrorReporter
+func timeoutHandler(h http.Handler, d time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), d)
+		defer cancel()
+		tw := &timeoutWriter{w: w, h: make(http.Header)}
+		// handler在超时后可能继续运行,使用请求和Context的副本,
+		// 不修改调用方在记录指标和访问日志时读取的Context,通过c.w的写入也进入缓冲
+		c, _ := r.Context().Value(contextKey).(*Context)
+		var hc Context
+		if c != nil {
+			hc = *c
+			hc.w = tw
+			ctx = context.WithValue(ctx, contextKey, &hc)
+		}
+		// handler在超时前结束时,将其设置的内容(如gatt函数名)同步回调用方的Context
+		syncContext := func() {
+			if c != nil {
+				hc.w = c.w
+				*c = hc
+			}
+		}
+		tr := r.Clone(ctx)
+		done := make(chan struct{})
+		panicCh := make(chan any, 1)
+		go func() {
+			defer func() {
+				if v := recover(); v != nil {
+					if v != http.ErrAbortHandler {
+						v = &handlerPanic{value: v, stack: debug.Stack()}
+					}
+					panicCh <- v
+				}
+			}()
+			h.ServeHTTP(tw, tr)
+			close(done)
+		}()
+		select {
+		case v := <-panicCh:
+			syncContext()
+			panic(v)
+		case <-done:
+			syncContext()
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+			dst := w.Header()
+			for k, vv := range tw.h {
+				dst[k] = vv
+			}
+			if !tw.wroteHeader {
+				tw.code = http.StatusOK
+			}
+			w.WriteHeader(tw.code)
+			w.Write(tw.buf.Bytes())
+		case <-ctx.Done():
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+			tw.timedOut = true
+			// r为未设置超时的原始请求,客户端断开时不响应也不上报
+			if r.Context().Err() == nil {
+				WriteError(w, r, &HTTPError{Status: http.StatusGatewayTimeout, Err: fmt.Errorf("timeout after %v: %w", d, ctx.Err())})
+			}
+		}
+	})
+}
+
+// timeoutWriter 缓冲限时handler的响应,超时后的写入返回http.ErrHandlerTimeout
+type timeoutWriter struct {
+	w    http.ResponseWriter
+	h    http.Header
+	mu   sync.Mutex
+	buf  bytes.Buffer
+	code int
+
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.h
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if !tw.wroteHeader {
+		tw.writeHeaderLocked(http.StatusOK)
+	}
+	return tw.buf.Write(p)
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+	tw.writeHeaderLocked(code)
+}
+
+func (tw *timeoutWriter) writeHeaderLocked(code int) {
+	tw.wroteHeader = true
+	tw.code = code
+}
+
+// Status 返回已写入缓冲的状态码,使鉴权、中间件等依赖ResponseWriter状态的代码在限时路由中同样可用
+func (tw *timeoutWriter) Status() int {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	return tw.code
+}
+
+// Size 返回已写入缓冲的长度
+func (tw *timeoutWriter) Size() int {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	return tw.buf.Len()
+}
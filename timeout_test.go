@@ -0,0 +1,56 @@
+package faas
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeout(t *testing.T) {
+	app := New()
+	app.HandleFunc("api", "path", "/fast", WithContextHandler(func(w http.ResponseWriter, r *http.Request, c *Context) {
+		c.w.Header().Set("X-Handler", "fast")
+		c.w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("fast"))
+	}), Timeout(time.Second))
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fast", nil))
+	if w.Code != http.StatusCreated || w.Body.String() != "fast" || w.Header().Get("X-Handler") != "fast" {
+		t.Errorf("got %d %q %v, want 201 fast", w.Code, w.Body.String(), w.Header())
+	}
+}
+
+func TestTimeoutOverrun(t *testing.T) {
+	app := New()
+	finished := make(chan error, 1)
+	app.HandleFunc("api", "prefix", "/slow", WithContextHandler(func(w http.ResponseWriter, r *http.Request, c *Context) {
+		<-r.Context().Done()
+		// 超时后继续运行,修改Context并通过c.w和w写入
+		time.Sleep(20 * time.Millisecond)
+		c.SubPath = "changed"
+		c.w.Header().Set("X-Late", "1")
+		c.w.WriteHeader(http.StatusOK)
+		_, err := c.w.Write([]byte("late"))
+		w.Write([]byte("late"))
+		finished <- err
+	}), Timeout(10*time.Millisecond))
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow/a", nil))
+	if w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("status = %d, want 504", w.Code)
+	}
+	select {
+	case err := <-finished:
+		if err != http.ErrHandlerTimeout {
+			t.Errorf("late write error = %v, want http.ErrHandlerTimeout", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler did not finish")
+	}
+	if w.Code != http.StatusGatewayTimeout || w.Header().Get("X-Late") != "" || w.Body.String() == "late" {
+		t.Errorf("late write reached the client: %d %v %q", w.Code, w.Header(), w.Body.String())
+	}
+}
@@ -0,0 +1,323 @@
+package faas
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+	_ "time/tzdata"
+)
+
+// ConcurrencyPolicy 定时函数上一次执行还未结束时再次触发的处理方式
+type ConcurrencyPolicy string
+
+const (
+	// ConcurrencySkip 跳过本次执行
+	ConcurrencySkip ConcurrencyPolicy = "skip"
+	// ConcurrencyQueue 最多排队一次,上一次结束后立即执行,默认策略
+	ConcurrencyQueue ConcurrencyPolicy = "queue"
+	// ConcurrencyParallel 允许并行执行
+	ConcurrencyParallel ConcurrencyPolicy = "parallel"
+)
+
+type timing struct {
+	app        *App
+	name       string
+	timingType string
+	interval   string
+	handler    func(env map[string]any)
+	loc        *time.Location
+	policy     ConcurrencyPolicy
+
+	mu       sync.Mutex
+	running  int
+	pending  bool
+	runs     int64
+	skipped  int64
+	delayed  int64
+	failures int64
+	//下一次触发的时间,用于路由表
+	next time.Time
+}
+
+// TimingStats 定时函数的执行统计
+type TimingStats struct {
+	Name     string
+	Type     string
+	Interval string
+	Policy   ConcurrencyPolicy
+	Running  int
+	Runs     int64
+	Skipped  int64
+	Delayed  int64
+	Failures int64
+}
+
+// TimingOption 定时函数的可选配置
+type TimingOption func(t *timing)
+
+// WithTimeZone 指定everyday和cron计算触发时间使用的时区,如Asia/Shanghai,默认为本地时区
+func WithTimeZone(tz string) TimingOption {
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		panic("bad time zone " + tz + ": " + err.Error())
+	}
+	return func(t *timing) {
+		t.loc = loc
+	}
+}
+
+// WithConcurrency 指定定时函数的并发策略
+func WithConcurrency(policy ConcurrencyPolicy) TimingOption {
+	if policy != ConcurrencySkip && policy != ConcurrencyQueue && policy != ConcurrencyParallel {
+		panic("bad concurrency policy " + string(policy) + ", only support skip/queue/parallel")
+	}
+	return func(t *timing) {
+		t.policy = policy
+	}
+}
+
+// TimingFunc 注册定时函数,定时函数在Run时开始调度,
+// env["ctx"]为服务的context.Context,退出时被取消,带有本次执行的span,可用StartSpan创建子span或InjectTrace传递
+func (a *App) TimingFunc(timingType, interval string, handler func(env map[string]any), opts ...TimingOption) {
+	a.logger().Info("registering timing", "type", timingType, "interval", interval)
+	t := &timing{
+		app:        a,
+		name:       funcName(handler),
+		timingType: timingType,
+		interval:   interval,
+		handler:    handler,
+		loc:        time.Local,
+		policy:     ConcurrencyQueue,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	a.timings = append(a.timings, t)
+}
+
+// TimingStats 返回所有定时函数的执行统计
+func (a *App) TimingStats() []TimingStats {
+	stats := make([]TimingStats, 0, len(a.timings))
+	for _, t := range a.timings {
+		t.mu.Lock()
+		stats = append(stats, TimingStats{
+			Name:     t.name,
+			Type:     t.timingType,
+			Interval: t.interval,
+			Policy:   t.policy,
+			Running:  t.running,
+			Runs:     t.runs,
+			Skipped:  t.skipped,
+			Delayed:  t.delayed,
+			Failures: t.failures,
+		})
+		t.mu.Unlock()
+	}
+	return stats
+}
+
+func (t *timing) start(ctx context.Context, wg *sync.WaitGroup) {
+	timingType, interval := t.timingType, t.interval
+	env := make(map[string]any)
+	env["trigertype"] = timingType
+	env["interval"] = interval
+	env["ctx"] = ctx
+	switch timingType {
+	case "repeat":
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			duration, err := time.ParseDuration(interval)
+			if err != nil {
+				t.logger().Error("bad repeat interval", "error", err)
+				return
+			}
+			timer := time.NewTicker(duration)
+			defer timer.Stop()
+			t.setNext(time.Now().Add(duration))
+			t.trigger(ctx, wg, env)
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case now := <-timer.C:
+					t.setNext(now.Add(duration))
+					t.trigger(ctx, wg, env)
+				}
+			}
+		}()
+	case "everyday":
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h, m, sec, err := ParseTimeOfDay(interval)
+			if err != nil {
+				t.logger().Error("bad everyday time", "error", err)
+				return
+			}
+			for {
+				now := time.Now().In(t.loc)
+				targetTime := nextTimeOfDay(now, h, m, sec)
+				sleepDuration := targetTime.Sub(now)
+				t.logger().Info("next execution", "at", targetTime, "sleep", sleepDuration)
+				t.setNext(targetTime)
+				if !sleep(ctx, sleepDuration) {
+					return
+				}
+				t.trigger(ctx, wg, env)
+			}
+		}()
+	case "cron":
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cron, err := ParseCron(interval)
+			if err != nil {
+				t.logger().Error("bad cron expression", "error", err)
+				return
+			}
+			for {
+				now := time.Now().In(t.loc)
+				targetTime := cron.Next(now)
+				sleepDuration := targetTime.Sub(now)
+				t.logger().Info("next execution", "at", targetTime, "sleep", sleepDuration)
+				t.setNext(targetTime)
+				if !sleep(ctx, sleepDuration) {
+					return
+				}
+				t.trigger(ctx, wg, env)
+			}
+		}()
+	case "once":
+		t.trigger(ctx, wg, env)
+	}
+}
+
+func (t *timing) setNext(next time.Time) {
+	t.mu.Lock()
+	t.next = next
+	t.mu.Unlock()
+}
+
+// logger 返回带有定时函数名称和类型的Logger
+func (t *timing) logger() *slog.Logger {
+	return t.app.logger().With("timing", t.name, "type", t.timingType, "interval", t.interval)
+}
+
+// trigger 按并发策略执行一次定时函数,不阻塞调度
+func (t *timing) trigger(ctx context.Context, wg *sync.WaitGroup, env map[string]any) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.running == 0 || t.policy == ConcurrencyParallel {
+		t.running++
+		wg.Add(1)
+		go t.exec(ctx, wg, env)
+		return
+	}
+	if t.policy == ConcurrencyQueue && !t.pending {
+		t.pending = true
+		t.delayed++
+		t.logger().Warn("timing function is still running, execution delayed")
+		return
+	}
+	t.skipped++
+	t.logger().Warn("timing function is still running, execution skipped")
+}
+
+func (t *timing) exec(ctx context.Context, wg *sync.WaitGroup, env map[string]any) {
+	defer wg.Done()
+	for {
+		start := time.Now()
+		spanCtx, span := t.app.tracer.start(ctx, "timing "+t.name, SpanKindInternal)
+		span.SetAttributes("faas.timing.type", t.timingType, "faas.timing.interval", t.interval)
+		// 每次执行使用env的副本,env["ctx"]带有本次执行的span,并发执行时互不影响
+		runEnv := maps.Clone(env)
+		runEnv["ctx"] = spanCtx
+		err := safeCall("timing", t.name, func() error {
+			t.handler(runEnv)
+			return nil
+		})
+		t.app.metrics.observeTiming(t.name, time.Since(start))
+		if err != nil {
+			span.RecordError(err)
+			t.app.reportError(err)
+		}
+		span.End()
+		t.mu.Lock()
+		t.runs++
+		if err != nil {
+			t.failures++
+		}
+		if t.pending && ctx.Err() == nil {
+			t.pending = false
+			t.mu.Unlock()
+			continue
+		}
+		t.pending = false
+		t.running--
+		t.mu.Unlock()
+		return
+	}
+}
+
+// nextTimeOfDay 返回now之后(不含now)第一个h:m:sec时刻,使用now所在的时区。
+// 按日期重新计算而不是加24小时,夏令时切换的当天也在同一钟点执行
+func nextTimeOfDay(now time.Time, h, m, sec int) time.Time {
+	target := timeOfDay(now.Year(), now.Month(), now.Day(), h, m, sec, now.Location())
+	if !now.Before(target) {
+		target = timeOfDay(now.Year(), now.Month(), now.Day()+1, h, m, sec, now.Location())
+	}
+	return target
+}
+
+// timeOfDay 与time.Date相同,但钟点在夏令时开始时跳过的时段内时顺延跳过的时长,
+// 如America/New_York的02:30顺延到03:30,time.Date可能返回更早的01:30;
+// 夏令时结束时重复的钟点取第一次
+func timeOfDay(year int, month time.Month, day, h, m, sec int, loc *time.Location) time.Time {
+	t := time.Date(year, month, day, h, m, sec, 0, loc)
+	if t.Hour() == h && t.Minute() == m && t.Second() == sec {
+		return t
+	}
+	// 按切换前的偏移计算,得到切换后对应的时刻
+	_, before := t.Add(-12 * time.Hour).Zone()
+	wall := time.Date(year, month, day, h, m, sec, 0, time.UTC)
+	return wall.Add(-time.Duration(before) * time.Second).In(loc)
+}
+
+// sleep 等待d时长,ctx提前结束时返回false
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// ParseTimeOfDay 解析一天中的时刻,支持13h、13h30m这样的时长形式和13:30、13:30:15这样的钟点形式
+func ParseTimeOfDay(s string) (hour, min, sec int, err error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, ":") {
+		layout := "15:04"
+		if strings.Count(s, ":") == 2 {
+			layout = "15:04:05"
+		}
+		tm, err := time.Parse(layout, s)
+		if err != nil {
+			return 0, 0, 0, fmt.Errorf("bad time of day %q", s)
+		}
+		return tm.Hour(), tm.Minute(), tm.Second(), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 || d >= 24*time.Hour {
+		return 0, 0, 0, fmt.Errorf("bad time of day %q", s)
+	}
+	total := int(d / time.Second)
+	return total / 3600, total / 60 % 60, total % 60, nil
+}
@@ -0,0 +1,169 @@
+package faas
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTimingConcurrency(t *testing.T) {
+	tests := []struct {
+		policy        ConcurrencyPolicy
+		started       int
+		runs, skipped int64
+		delayed       int64
+		maxRunning    int32
+	}{
+		{ConcurrencySkip, 1, 1, 2, 0, 1},
+		{ConcurrencyQueue, 1, 2, 1, 1, 1},
+		{ConcurrencyParallel, 3, 3, 0, 0, 3},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			started := make(chan struct{}, 3)
+			release := make(chan struct{})
+			var running, maxRunning atomic.Int32
+			app := New()
+			app.TimingFunc("once", "", func(env map[string]any) {
+				n := running.Add(1)
+				for m := maxRunning.Load(); n > m && !maxRunning.CompareAndSwap(m, n); m = maxRunning.Load() {
+				}
+				started <- struct{}{}
+				<-release
+				running.Add(-1)
+			}, WithConcurrency(tt.policy))
+			tm := app.timings[0]
+
+			var wg sync.WaitGroup
+			env := map[string]any{"ctx": context.Background()}
+			// 第一次执行阻塞时再触发两次
+			for i := 0; i < 3; i++ {
+				tm.trigger(context.Background(), &wg, env)
+			}
+			for i := 0; i < tt.started; i++ {
+				select {
+				case <-started:
+				case <-time.After(5 * time.Second):
+					t.Fatalf("only %d executions started, want %d", i, tt.started)
+				}
+			}
+			close(release)
+			wg.Wait()
+
+			stats := app.TimingStats()[0]
+			if stats.Runs != tt.runs || stats.Skipped != tt.skipped || stats.Delayed != tt.delayed || stats.Running != 0 {
+				t.Errorf("stats = %+v, want runs %d skipped %d delayed %d", stats, tt.runs, tt.skipped, tt.delayed)
+			}
+			if got := maxRunning.Load(); got != tt.maxRunning {
+				t.Errorf("max concurrent executions = %d, want %d", got, tt.maxRunning)
+			}
+		})
+	}
+}
+
+func TestTimingQueueCancelled(t *testing.T) {
+	release := make(chan struct{})
+	var runs atomic.Int32
+	app := New()
+	app.TimingFunc("once", "", func(env map[string]any) {
+		runs.Add(1)
+		<-release
+	}, WithConcurrency(ConcurrencyQueue))
+	tm := app.timings[0]
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	env := map[string]any{"ctx": ctx}
+	tm.trigger(ctx, &wg, env)
+	tm.trigger(ctx, &wg, env)
+	// 退出时不再执行排队的一次
+	cancel()
+	close(release)
+	wg.Wait()
+	if n := runs.Load(); n != 1 {
+		t.Errorf("runs = %d, want 1", n)
+	}
+}
+
+func TestParseTimeOfDay(t *testing.T) {
+	tests := []struct {
+		in      string
+		h, m, s int
+		err     bool
+	}{
+		{"13h", 13, 0, 0, false},
+		{"13h30m", 13, 30, 0, false},
+		{"13h30m15s", 13, 30, 15, false},
+		{"90m", 1, 30, 0, false},
+		{"0s", 0, 0, 0, false},
+		{"13:30", 13, 30, 0, false},
+		{"9:05", 9, 5, 0, false},
+		{" 13:30:15 ", 13, 30, 15, false},
+		{"00:00", 0, 0, 0, false},
+		{"23:59:59", 23, 59, 59, false},
+		{"24h", 0, 0, 0, true},
+		{"-1h", 0, 0, 0, true},
+		{"24:00", 0, 0, 0, true},
+		{"13:60", 0, 0, 0, true},
+		{"13:30:15:00", 0, 0, 0, true},
+		{"1pm", 0, 0, 0, true},
+		{"", 0, 0, 0, true},
+	}
+	for _, tt := range tests {
+		h, m, s, err := ParseTimeOfDay(tt.in)
+		if tt.err {
+			if err == nil {
+				t.Errorf("ParseTimeOfDay(%q) = %d:%d:%d, want error", tt.in, h, m, s)
+			}
+			continue
+		}
+		if err != nil || h != tt.h || m != tt.m || s != tt.s {
+			t.Errorf("ParseTimeOfDay(%q) = %d:%d:%d, %v, want %d:%d:%d", tt.in, h, m, s, err, tt.h, tt.m, tt.s)
+		}
+	}
+}
+
+func TestNextTimeOfDay(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(s string) time.Time {
+		tm, err := time.ParseInLocation("2006-01-02 15:04:05 MST", s, ny)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+	tests := []struct {
+		name    string
+		now     string
+		h, m    int
+		want    string
+		elapsed time.Duration
+	}{
+		{"later today", "2024-06-01 09:00:00 EDT", 13, 30, "2024-06-01 13:30:00 EDT", 4*time.Hour + 30*time.Minute},
+		{"tomorrow", "2024-06-01 14:00:00 EDT", 13, 30, "2024-06-02 13:30:00 EDT", 23*time.Hour + 30*time.Minute},
+		{"exactly now", "2024-06-01 13:30:00 EDT", 13, 30, "2024-06-02 13:30:00 EDT", 24 * time.Hour},
+		// 夏令时开始的一天只有23小时,仍在13:30执行
+		{"spring forward", "2024-03-09 14:00:00 EST", 13, 30, "2024-03-10 13:30:00 EDT", 22*time.Hour + 30*time.Minute},
+		// 夏令时结束的一天有25小时
+		{"fall back", "2024-11-02 14:00:00 EDT", 13, 30, "2024-11-03 13:30:00 EST", 24*time.Hour + 30*time.Minute},
+		// 跳过的02:30顺延到03:30
+		{"skipped time", "2024-03-10 01:00:00 EST", 2, 30, "2024-03-10 03:30:00 EDT", time.Hour + 30*time.Minute},
+		{"skipped time passed", "2024-03-10 03:40:00 EDT", 2, 30, "2024-03-11 02:30:00 EDT", 22*time.Hour + 50*time.Minute},
+		{"before skipped time", "2024-03-10 01:40:00 EST", 2, 30, "2024-03-10 03:30:00 EDT", 50 * time.Minute},
+		// 重复的01:30只在第一次执行
+		{"repeated time", "2024-11-03 00:00:00 EDT", 1, 30, "2024-11-03 01:30:00 EDT", time.Hour + 30*time.Minute},
+		{"after repeated time", "2024-11-03 01:30:00 EDT", 1, 30, "2024-11-04 01:30:00 EST", 25 * time.Hour},
+	}
+	for _, tt := range tests {
+		now := at(tt.now)
+		got := nextTimeOfDay(now, tt.h, tt.m, 0)
+		if !got.Equal(at(tt.want)) || got.Sub(now) != tt.elapsed {
+			t.Errorf("%s: nextTimeOfDay(%s) = %s (in %v), want %s (in %v)", tt.name, tt.now, got.Format("2006-01-02 15:04:05 MST"), got.Sub(now), tt.want, tt.elapsed)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package faas
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraceParentHeader W3C Trace Context的请求头
+const TraceParentHeader = "traceparent"
+
+// TraceID 16字节的trace ID
+type TraceID [16]byte
+
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+// IsValid 全0的trace ID无效
+func (t TraceID) IsValid() bool {
+	return t != TraceID{}
+}
+
+// SpanID 8字节的span ID
+type SpanID [8]byte
+
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// IsValid 全0的span ID无效
+func (s SpanID) IsValid() bool {
+	return s != SpanID{}
+}
+
+// SpanKind span的类型,取值与OpenTelemetry相同
+type SpanKind int
+
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+	SpanKindClient   SpanKind = 3
+)
+
+// SpanContext 跨服务传递的trace信息
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// TraceParent 返回traceparent请求头的值
+func (sc SpanContext) TraceParent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// ParseTraceParent 解析traceparent请求头,格式错误时ok为false
+func ParseTraceParent(s string) (sc SpanContext, ok bool) {
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return sc, false
+	}
+	if len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return sc, false
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return sc, false
+	}
+	sc.Sampled = flags[0]&1 == 1
+	return sc, sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// Span 一次操作的耗时和属性,End后交给App.TraceExporter导出
+type Span struct {
+	tracer *tracer
+	sc     SpanContext
+	parent SpanID
+	name   string
+	kind   SpanKind
+	start  time.Time
+
+	mu    sync.Mutex
+	attrs map[string]any
+	err   error
+	ended bool
+}
+
+type spanKey struct{}
+
+// SpanFromContext 返回ctx中的Span,没有时返回nil
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// StartSpan 在ctx中的Span下创建子Span,ctx中没有Span时创建新的trace,使用完后调用End
+func StartSpan(ctx context.Context, name string) (context.Context, *Span) {
+	t := defaultApp.tracer
+	if parent := SpanFromContext(ctx); parent != nil {
+		t = parent.tracer
+	}
+	return t.start(ctx, name, SpanKindInternal)
+}
+
+// SpanContext 返回span的trace信息
+func (s *Span) SpanContext() SpanContext {
+	return s.sc
+}
+
+// SetAttributes 按key、value交替的顺序设置属性
+func (s *Span) SetAttributes(kv ...any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i+1 < len(kv); i += 2 {
+		if k, ok := kv[i].(string); ok {
+			s.attrs[k] = kv[i+1]
+		}
+	}
+}
+
+// RecordError 将span标记为失败
+func (s *Span) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+}
+
+// End 结束span,重复调用无效
+func (s *Span) End() {
+	end := time.Now()
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	data := SpanData{
+		Name:         s.name,
+		Kind:         s.kind,
+		TraceID:      s.sc.TraceID,
+		SpanID:       s.sc.SpanID,
+		ParentSpanID: s.parent,
+		Start:        s.start,
+		End:          end,
+		Attributes:   s.attrs,
+	}
+	if s.err != nil {
+		data.Error = s.err.Error()
+	}
+	s.mu.Unlock()
+	if s.sc.Sampled {
+		s.tracer.export(data)
+	}
+}
+
+// start 创建span,ctx中有Span或远端的SpanContext时作为其子span
+func (t *tracer) start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	s := &Span{tracer: t, name: name, kind: kind, start: time.Now(), attrs: make(map[string]any)}
+	if parent := SpanFromContext(ctx); parent != nil {
+		s.sc.TraceID, s.parent, s.sc.Sampled = parent.sc.TraceID, parent.sc.SpanID, parent.sc.Sampled
+	} else if remote, ok := ctx.Value(remoteSpanKey{}).(SpanContext); ok {
+		s.sc.TraceID, s.parent, s.sc.Sampled = remote.TraceID, remote.SpanID, remote.Sampled
+	} else {
+		rand.Read(s.sc.TraceID[:])
+		s.sc.Sampled = true
+	}
+	rand.Read(s.sc.SpanID[:])
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+type remoteSpanKey struct{}
+
+// extractTrace 从请求头中取出上游的SpanContext
+func extractTrace(ctx context.Context, h http.Header) context.Context {
+	if sc, ok := ParseTraceParent(h.Get(TraceParentHeader)); ok {
+		return context.WithValue(ctx, remoteSpanKey{}, sc)
+	}
+	return ctx
+}
+
+// InjectTrace 将ctx中的Span写入traceparent请求头,调用其他沙箱(如IscUrl)时使用以串联trace
+func InjectTrace(ctx context.Context, h http.Header) {
+	if s := SpanFromContext(ctx); s != nil {
+		h.Set(TraceParentHeader, s.sc.TraceParent())
+	}
+}
+
+// TracingTransport 为每个请求创建client span并写入traceparent请求头,base为nil时使用http.DefaultTransport
+func TracingTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		t := defaultApp.tracer
+		if parent := SpanFromContext(r.Context()); parent != nil {
+			t = parent.tracer
+		}
+		ctx, span := t.start(r.Context(), r.Method+" "+r.URL.Host, SpanKindClient)
+		defer span.End()
+		span.SetAttributes("http.method", r.Method, "http.url", r.URL.String())
+		r = r.Clone(ctx)
+		InjectTrace(ctx, r.Header)
+		resp, err := base.RoundTrip(r)
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		span.SetAttributes("http.status_code", resp.StatusCode)
+		if resp.StatusCode >= http.StatusInternalServerError {
+			span.RecordError(&HTTPError{Status: resp.StatusCode})
+		}
+		return resp, nil
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// startSpan 在请求的context中创建子span并替换请求
+func startSpan(r *http.Request, name string) (*http.Request, *Span) {
+	c, _ := r.Context().Value(contextKey).(*Context)
+	t := defaultApp.tracer
+	if c != nil && c.app != nil {
+		t = c.app.tracer
+	}
+	ctx, span := t.start(r.Context(), name, SpanKindInternal)
+	return r.WithContext(ctx), span
+}
+
+// endSpan 结束请求的server span,5xx时标记为失败
+func (a *App) endSpan(c *Context, r *http.Request, status int) {
+	span := c.span
+	if span == nil {
+		return
+	}
+	span.mu.Lock()
+	span.name = r.Method
+	if c.route != "" {
+		span.name += " " + c.route
+	}
+	span.mu.Unlock()
+	span.SetAttributes(
+		"http.method", r.Method,
+		"http.target", c.oriPath,
+		"http.route", c.route,
+		"http.status_code", status,
+		"faas.entry", c.Entry,
+		"faas.request_id", c.RequestID,
+	)
+	if status >= http.StatusInternalServerError {
+		span.RecordError(&HTTPError{Status: status})
+	}
+	span.End()
+}
@@ -0,0 +1,312 @@
+package faas
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SpanData 结束的span,交给SpanExporter导出
+type SpanData struct {
+	Name         string
+	Kind         SpanKind
+	TraceID      TraceID
+	SpanID       SpanID
+	ParentSpanID SpanID
+	Start        time.Time
+	End          time.Time
+	Attributes   map[string]any
+	//失败时的错误信息,成功时为空
+	Error string
+}
+
+// SpanExporter 批量导出span
+type SpanExporter interface {
+	ExportSpans(ctx context.Context, spans []SpanData) error
+}
+
+const (
+	traceQueueSize  = 2048
+	traceBatchSize  = 512
+	traceBatchDelay = 2 * time.Second
+)
+
+// tracer 缓冲结束的span并在后台批量导出,队列满时丢弃
+type tracer struct {
+	app     *App
+	once    sync.Once
+	queue   chan SpanData
+	flushCh chan chan struct{}
+}
+
+func newTracer(a *App) *tracer {
+	return &tracer{app: a, queue: make(chan SpanData, traceQueueSize), flushCh: make(chan chan struct{})}
+}
+
+func (t *tracer) export(data SpanData) {
+	if t.app.TraceExporter == nil {
+		return
+	}
+	t.once.Do(func() {
+		go t.loop()
+	})
+	select {
+	case t.queue <- data:
+	default:
+	}
+}
+
+func (t *tracer) loop() {
+	ticker := time.NewTicker(traceBatchDelay)
+	defer ticker.Stop()
+	var batch []SpanData
+	send := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := t.app.TraceExporter.ExportSpans(ctx, batch); err != nil {
+			t.app.logger().Warn("export spans failed", "count", len(batch), "error", err)
+		}
+		cancel()
+		batch = nil
+	}
+	for {
+		select {
+		case data := <-t.queue:
+			batch = append(batch, data)
+			if len(batch) >= traceBatchSize {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		case ack := <-t.flushCh:
+			for n := len(t.queue); n > 0; n-- {
+				batch = append(batch, <-t.queue)
+			}
+			send()
+			close(ack)
+		}
+	}
+}
+
+// flush 导出已缓冲的span,服务退出时调用
+func (t *tracer) flush(ctx context.Context) {
+	if t.app.TraceExporter == nil {
+		return
+	}
+	t.once.Do(func() {
+		go t.loop()
+	})
+	ack := make(chan struct{})
+	select {
+	case t.flushCh <- ack:
+	case <-ctx.Done():
+		return
+	}
+	select {
+	case <-ack:
+	case <-ctx.Done():
+	}
+}
+
+// newTraceExporter 按环境变量SU_TRACE_EXPORTER创建导出器:
+// otlp导出到OTEL_EXPORTER_OTLP_ENDPOINT(默认http://localhost:4318),
+// stdout输出到标准输出,file输出到LogDir下的trace.log,为空时不导出
+func (a *App) newTraceExporter() SpanExporter {
+	switch os.Getenv("SU_TRACE_EXPORTER") {
+	case "otlp":
+		endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+		if endpoint == "" {
+			base := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+			if base == "" {
+				base = "http://localhost:4318"
+			}
+			endpoint = base + "/v1/traces"
+		}
+		return NewOTLPExporter(endpoint, nil)
+	case "stdout":
+		return NewJSONExporter(os.Stdout)
+	case "file":
+		f, err := os.OpenFile(filepath.Join(os.Getenv("LOG_PATH"), "trace.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			a.logger().Error("open trace file failed, tracing is disabled", "error", err)
+			return nil
+		}
+		return NewJSONExporter(f)
+	}
+	return nil
+}
+
+// serviceName 返回上报的服务名,默认取环境变量OTEL_SERVICE_NAME,为空时为程序名
+func serviceName() string {
+	if name := os.Getenv("OTEL_SERVICE_NAME"); name != "" {
+		return name
+	}
+	return filepath.Base(os.Args[0])
+}
+
+type otlpExporter struct {
+	endpoint string
+	headers  map[string]string
+	client   *http.Client
+	service  string
+}
+
+// NewOTLPExporter 创建以OTLP/HTTP JSON格式导出到endpoint(如http://localhost:4318/v1/traces)的导出器
+func NewOTLPExporter(endpoint string, headers map[string]string) SpanExporter {
+	return &otlpExporter{endpoint: endpoint, headers: headers, client: &http.Client{}, service: serviceName()}
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+type otlpAttr struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpSpan struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              SpanKind   `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []otlpAttr `json:"attributes,omitempty"`
+	Status            struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	} `json:"status"`
+}
+
+func otlpAttrs(attrs map[string]any) []otlpAttr {
+	list := make([]otlpAttr, 0, len(attrs))
+	for _, k := range sortedKeys(attrs) {
+		var v otlpValue
+		switch x := attrs[k].(type) {
+		case string:
+			v.StringValue = &x
+		case bool:
+			v.BoolValue = &x
+		case int:
+			s := strconv.Itoa(x)
+			v.IntValue = &s
+		case int64:
+			s := strconv.FormatInt(x, 10)
+			v.IntValue = &s
+		case float64:
+			v.DoubleValue = &x
+		default:
+			s := fmt.Sprint(x)
+			v.StringValue = &s
+		}
+		list = append(list, otlpAttr{Key: k, Value: v})
+	}
+	return list
+}
+
+func (e *otlpExporter) ExportSpans(ctx context.Context, spans []SpanData) error {
+	list := make([]otlpSpan, len(spans))
+	for i, s := range spans {
+		span := otlpSpan{
+			TraceID:           s.TraceID.String(),
+			SpanID:            s.SpanID.String(),
+			Name:              s.Name,
+			Kind:              s.Kind,
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+			Attributes:        otlpAttrs(s.Attributes),
+		}
+		if s.ParentSpanID.IsValid() {
+			span.ParentSpanID = s.ParentSpanID.String()
+		}
+		if s.Error != "" {
+			span.Status.Code, span.Status.Message = 2, s.Error
+		}
+		list[i] = span
+	}
+	payload := map[string]any{
+		"resourceSpans": []any{map[string]any{
+			"resource": map[string]any{"attributes": otlpAttrs(map[string]any{"service.name": e.service})},
+			"scopeSpans": []any{map[string]any{
+				"scope": map[string]any{"name": "github.com/faasteam/faas"},
+				"spans": list,
+			}},
+		}},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("otlp export: %s", resp.Status)
+	}
+	return nil
+}
+
+type jsonExporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONExporter 创建将每个span按一行JSON写入w的导出器
+func NewJSONExporter(w io.Writer) SpanExporter {
+	return &jsonExporter{w: w}
+}
+
+func (e *jsonExporter) ExportSpans(ctx context.Context, spans []SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	enc := json.NewEncoder(e.w)
+	for _, s := range spans {
+		line := map[string]any{
+			"trace_id":    s.TraceID.String(),
+			"span_id":     s.SpanID.String(),
+			"name":        s.Name,
+			"kind":        s.Kind,
+			"start":       s.Start,
+			"duration_ms": float64(s.End.Sub(s.Start).Microseconds()) / 1000,
+		}
+		if s.ParentSpanID.IsValid() {
+			line["parent_span_id"] = s.ParentSpanID.String()
+		}
+		if len(s.Attributes) != 0 {
+			line["attributes"] = s.Attributes
+		}
+		if s.Error != "" {
+			line["error"] = s.Error
+		}
+		if err := enc.Encode(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package faas
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	tests := []struct {
+		in      string
+		ok      bool
+		sampled bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", true, false},
+		{" 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01 ", true, true},
+		// 未来的版本可以有更多字段
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", true, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false, false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01", false, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e473x-00f067aa0ba902b7-01", false, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0x", false, false},
+		{"", false, false},
+	}
+	for _, tt := range tests {
+		sc, ok := ParseTraceParent(tt.in)
+		if ok != tt.ok || (ok && sc.Sampled != tt.sampled) {
+			t.Errorf("ParseTraceParent(%q) = sampled %v, %v, want sampled %v, %v", tt.in, sc.Sampled, ok, tt.sampled, tt.ok)
+		}
+		if in := strings.TrimSpace(tt.in); ok && strings.HasPrefix(in, "00-") && sc.TraceParent() != in {
+			t.Errorf("TraceParent() = %q, want %q", sc.TraceParent(), in)
+		}
+	}
+}
+
+const remoteTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestTracePropagation(t *testing.T) {
+	app := New()
+	var outgoing string
+	app.HandleFunc("api", "path", "/call", func(w http.ResponseWriter, r *http.Request) {
+		h := make(http.Header)
+		InjectTrace(r.Context(), h)
+		outgoing = h.Get(TraceParentHeader)
+	})
+	r := httptest.NewRequest(http.MethodGet, "/call", nil)
+	r.Header.Set(TraceParentHeader, remoteTraceParent)
+	app.ServeHTTP(httptest.NewRecorder(), r)
+
+	remote, _ := ParseTraceParent(remoteTraceParent)
+	sc, ok := ParseTraceParent(outgoing)
+	if !ok {
+		t.Fatalf("outgoing traceparent %q is invalid", outgoing)
+	}
+	if sc.TraceID != remote.TraceID || sc.SpanID == remote.SpanID || !sc.Sampled {
+		t.Errorf("outgoing traceparent %q does not continue %q", outgoing, remoteTraceParent)
+	}
+}
+
+func TestTracingTransport(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(TraceParentHeader)
+	}))
+	defer srv.Close()
+
+	ctx, span := StartSpan(context.Background(), "parent")
+	defer span.End()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	resp, err := (&http.Client{Transport: TracingTransport(nil)}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	sc, ok := ParseTraceParent(got)
+	if !ok || sc.TraceID != span.SpanContext().TraceID || sc.SpanID == span.SpanContext().SpanID {
+		t.Errorf("traceparent %q is not a child of %q", got, span.SpanContext().TraceParent())
+	}
+}
+
+// collector 模拟OTLP/HTTP的接收端
+type collector struct {
+	mu       sync.Mutex
+	requests []*http.Request
+	payloads []otlpPayload
+}
+
+type otlpPayload struct {
+	ResourceSpans []struct {
+		Resource struct {
+			Attributes []otlpAttr `json:"attributes"`
+		} `json:"resource"`
+		ScopeSpans []struct {
+			Scope struct {
+				Name string `json:"name"`
+			} `json:"scope"`
+			Spans []otlpSpan `json:"spans"`
+		} `json:"scopeSpans"`
+	} `json:"resourceSpans"`
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var p otlpPayload
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	c.requests = append(c.requests, r)
+	c.payloads = append(c.payloads, p)
+	c.mu.Unlock()
+}
+
+func (c *collector) spans() []otlpSpan {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var spans []otlpSpan
+	for _, p := range c.payloads {
+		for _, rs := range p.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				spans = append(spans, ss.Spans...)
+			}
+		}
+	}
+	return spans
+}
+
+func TestOTLPExporter(t *testing.T) {
+	t.Setenv("OTEL_SERVICE_NAME", "faas-test")
+	c := &collector{}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	app := New()
+	app.TraceExporter = NewOTLPExporter(srv.URL+"/v1/traces", map[string]string{"Authorization": "Bearer token"})
+	app.HandleFunc("api", "path", "/fail", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	})
+	r := httptest.NewRequest(http.MethodGet, "/fail", nil)
+	r.Header.Set(TraceParentHeader, remoteTraceParent)
+	app.ServeHTTP(httptest.NewRecorder(), r)
+
+	// 批量导出的间隔远大于测试时长,只有退出时的flush会导出
+	if len(c.spans()) != 0 {
+		t.Fatalf("spans exported before flush")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	app.stop(ctx)
+
+	if len(c.requests) != 1 {
+		t.Fatalf("got %d export requests, want 1", len(c.requests))
+	}
+	req := c.requests[0]
+	if req.URL.Path != "/v1/traces" || req.Header.Get("Content-Type") != "application/json" || req.Header.Get("Authorization") != "Bearer token" {
+		t.Errorf("bad export request %s %v", req.URL.Path, req.Header)
+	}
+	p := c.payloads[0]
+	if len(p.ResourceSpans) != 1 || len(p.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("bad payload shape %+v", p)
+	}
+	attrs := p.ResourceSpans[0].Resource.Attributes
+	if len(attrs) != 1 || attrs[0].Key != "service.name" || *attrs[0].Value.StringValue != "faas-test" {
+		t.Errorf("bad resource attributes %+v", attrs)
+	}
+	if name := p.ResourceSpans[0].ScopeSpans[0].Scope.Name; name != "github.com/faasteam/faas" {
+		t.Errorf("scope name = %q", name)
+	}
+
+	spans := make(map[string]otlpSpan)
+	for _, s := range c.spans() {
+		spans[s.Name] = s
+	}
+	server, ok := spans["GET /fail"]
+	if !ok {
+		t.Fatalf("no server span in %v", spans)
+	}
+	if server.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || server.ParentSpanID != "00f067aa0ba902b7" || server.Kind != SpanKindServer {
+		t.Errorf("bad server span %+v", server)
+	}
+	if server.Status.Code != 2 {
+		t.Errorf("server span status = %d, want 2", server.Status.Code)
+	}
+	handler, ok := spans["handler /fail"]
+	if !ok {
+		t.Fatalf("no handler span in %v", spans)
+	}
+	if handler.TraceID != server.TraceID || handler.ParentSpanID == "" || handler.ParentSpanID == server.ParentSpanID {
+		t.Errorf("handler span %+v is not inside the server span", handler)
+	}
+}
+
+func TestTimingSpanContext(t *testing.T) {
+	app := New()
+	done := make(chan *Span, 1)
+	app.TimingFunc("once", "", func(env map[string]any) {
+		done <- SpanFromContext(env["ctx"].(context.Context))
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var wg sync.WaitGroup
+	app.timings[0].start(ctx, &wg)
+	select {
+	case span := <-done:
+		if span == nil || span.name != "timing "+app.timings[0].name {
+			t.Errorf("env[\"ctx\"] has span %v, want the timing span", span)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timing function did not run")
+	}
+	wg.Wait()
+}